@@ -0,0 +1,404 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"strings"
+)
+
+// Node is implemented by every node of the syntax tree.
+// Pos is the position of the first character of the node, End the position right behind it.
+type Node interface {
+	Pos() lsp.Position
+	End() lsp.Position
+}
+
+type Expr interface {
+	Node
+	exprNode()
+}
+
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Decl is a top level declaration: an include, a function or a global variable.
+type Decl interface {
+	Node
+	declNode()
+}
+
+/**
+ * File and comments
+ */
+type File struct {
+	URI string
+	Decls []Decl
+	Comments []*Comment
+	Start lsp.Position
+	Stop lsp.Position
+	// decls runs parallel to Decls and is kept for incremental reparsing.
+	decls []declInfo
+}
+
+type Comment struct {
+	Slash lsp.Position
+	Text string
+	Stop lsp.Position
+}
+
+/**
+ * Declarations
+ */
+type IncludeDirective struct {
+	Hash lsp.Position
+	// Path is the file name as written, without quotes or angle brackets.
+	Path string
+	PathStart lsp.Position
+	PathEnd lsp.Position
+	// Angled is set for `#include <file>`.
+	Angled bool
+}
+
+type FuncDecl struct {
+	Type *TypeExpr
+	Func lsp.Position
+	Name *Ident
+	Lparen lsp.Position
+	Params []*Param
+	Rparen lsp.Position
+	// Body is nil for a declaration of the generated catalog and when it is broken before its
+	// opening brace.
+	Body *BlockStmt
+	Stop lsp.Position
+}
+
+type Param struct {
+	Type *TypeExpr
+	ByRef bool
+	Name *Ident
+}
+
+// VarDecl declares a global variable or, inside a function, a local one.
+type VarDecl struct {
+	Type *TypeExpr
+	Name *Ident
+	// Value is nil when the variable has no initializer.
+	Value Expr
+	Stop lsp.Position
+}
+
+// BadDecl covers source that could not be parsed as a declaration.
+type BadDecl struct {
+	From lsp.Position
+	To lsp.Position
+}
+
+/**
+ * Types
+ */
+
+// TypeExpr is a type as written in a declaration: `long`, `list<string>`, `map<string,long>` or `[]string`.
+type TypeExpr struct {
+	Start lsp.Position
+	// Name is the base type name, it is empty for array types.
+	Name string
+	// Args holds the type arguments of generic types and the element type of arrays.
+	Args []*TypeExpr
+	Array bool
+	Stop lsp.Position
+}
+
+func (t *TypeExpr) String() string {
+	if t == nil {
+		return ""
+	}
+	if t.Array {
+		return "[]" + t.Args[0].String()
+	}
+	if len(t.Args) == 0 {
+		return t.Name
+	}
+	args := []string{}
+	for _, arg := range t.Args {
+		args = append(args, arg.String())
+	}
+	return t.Name + "<" + strings.Join(args, ",") + ">"
+}
+
+/**
+ * Expressions
+ */
+// Ident is a name. Where the parser expected a name and found none the Ident is kept
+// with an empty Name, so declarations never have a nil name.
+type Ident struct {
+	NamePos lsp.Position
+	Name string
+}
+
+type BasicLit struct {
+	ValuePos lsp.Position
+	// Kind is TOKEN_NUMBER, TOKEN_STRING or TOKEN_KEYWORD for true and false.
+	Kind TokenKind
+	// Value is the literal as written, strings keep their quotes.
+	Value string
+}
+
+type CallExpr struct {
+	Fun Expr
+	Lparen lsp.Position
+	Args []Expr
+	// Rparen is the position of the closing parenthesis, or where it is missing.
+	Rparen lsp.Position
+	Stop lsp.Position
+}
+
+// CallbackExpr is `callback Name`, which hands a script function to the runtime.
+type CallbackExpr struct {
+	Callback lsp.Position
+	Name *Ident
+}
+
+type ParenExpr struct {
+	Lparen lsp.Position
+	X Expr
+	Stop lsp.Position
+}
+
+type UnaryExpr struct {
+	OpPos lsp.Position
+	Op string
+	X Expr
+	// Postfix is set for `x++` and `x--`.
+	Postfix bool
+}
+
+type BinaryExpr struct {
+	X Expr
+	OpPos lsp.Position
+	Op string
+	Y Expr
+}
+
+// AssignExpr is an assignment with `=` or a compound operator. Assignments are
+// expressions, so they can appear in the head of a for loop.
+type AssignExpr struct {
+	Target Expr
+	OpPos lsp.Position
+	Op string
+	Value Expr
+}
+
+type IndexExpr struct {
+	X Expr
+	Lbrack lsp.Position
+	Index Expr
+	Stop lsp.Position
+}
+
+// MemberExpr is `x.Name`, used for method calls like `list.Add(...)`.
+type MemberExpr struct {
+	X Expr
+	Member *Ident
+}
+
+// BadExpr covers source that could not be parsed as an expression.
+type BadExpr struct {
+	From lsp.Position
+	To lsp.Position
+}
+
+/**
+ * Statements
+ */
+type BlockStmt struct {
+	Lbrace lsp.Position
+	List []Stmt
+	Stop lsp.Position
+}
+
+type ExprStmt struct {
+	X Expr
+	Stop lsp.Position
+}
+
+type EmptyStmt struct {
+	Semicolon lsp.Position
+}
+
+type IfStmt struct {
+	If lsp.Position
+	Cond Expr
+	Body Stmt
+	// Else is nil without an else branch.
+	Else Stmt
+}
+
+type WhileStmt struct {
+	While lsp.Position
+	Cond Expr
+	Body Stmt
+}
+
+// ForStmt is `for (Init; Cond; Post) Body`, every part of the head may be nil.
+type ForStmt struct {
+	For lsp.Position
+	Init Stmt
+	Cond Expr
+	Post Stmt
+	Body Stmt
+}
+
+type ReturnStmt struct {
+	Return lsp.Position
+	// Result is nil for a bare `return;`.
+	Result Expr
+	Stop lsp.Position
+}
+
+// BranchStmt is `break` or `continue`.
+type BranchStmt struct {
+	TokPos lsp.Position
+	Tok string
+	Stop lsp.Position
+}
+
+type BadStmt struct {
+	From lsp.Position
+	To lsp.Position
+}
+
+/**
+ * Positions
+ */
+func (n *File) Pos() lsp.Position { return n.Start }
+func (n *File) End() lsp.Position { return n.Stop }
+func (n *Comment) Pos() lsp.Position { return n.Slash }
+func (n *Comment) End() lsp.Position { return n.Stop }
+
+func (n *IncludeDirective) Pos() lsp.Position { return n.Hash }
+func (n *IncludeDirective) End() lsp.Position { return n.PathEnd }
+func (n *FuncDecl) Pos() lsp.Position {
+	if n.Type != nil {
+		return n.Type.Pos()
+	}
+	return n.Func
+}
+func (n *FuncDecl) End() lsp.Position { return n.Stop }
+func (n *Param) Pos() lsp.Position { return n.Type.Pos() }
+func (n *Param) End() lsp.Position { return n.Name.End() }
+func (n *VarDecl) Pos() lsp.Position { return n.Type.Pos() }
+func (n *VarDecl) End() lsp.Position { return n.Stop }
+func (n *BadDecl) Pos() lsp.Position { return n.From }
+func (n *BadDecl) End() lsp.Position { return n.To }
+
+func (n *TypeExpr) Pos() lsp.Position { return n.Start }
+func (n *TypeExpr) End() lsp.Position { return n.Stop }
+
+func (n *Ident) Pos() lsp.Position { return n.NamePos }
+func (n *Ident) End() lsp.Position {
+	return lsp.Position{Line: n.NamePos.Line, Character: n.NamePos.Character + len(n.Name)}
+}
+func (n *BasicLit) Pos() lsp.Position { return n.ValuePos }
+func (n *BasicLit) End() lsp.Position {
+	return lsp.Position{Line: n.ValuePos.Line, Character: n.ValuePos.Character + len(n.Value)}
+}
+func (n *CallExpr) Pos() lsp.Position { return n.Fun.Pos() }
+func (n *CallExpr) End() lsp.Position { return n.Stop }
+func (n *CallbackExpr) Pos() lsp.Position { return n.Callback }
+func (n *CallbackExpr) End() lsp.Position { return n.Name.End() }
+func (n *ParenExpr) Pos() lsp.Position { return n.Lparen }
+func (n *ParenExpr) End() lsp.Position { return n.Stop }
+func (n *UnaryExpr) Pos() lsp.Position {
+	if n.Postfix {
+		return n.X.Pos()
+	}
+	return n.OpPos
+}
+func (n *UnaryExpr) End() lsp.Position {
+	if n.Postfix {
+		return lsp.Position{Line: n.OpPos.Line, Character: n.OpPos.Character + len(n.Op)}
+	}
+	return n.X.End()
+}
+func (n *BinaryExpr) Pos() lsp.Position { return n.X.Pos() }
+func (n *BinaryExpr) End() lsp.Position { return n.Y.End() }
+func (n *AssignExpr) Pos() lsp.Position { return n.Target.Pos() }
+func (n *AssignExpr) End() lsp.Position { return n.Value.End() }
+func (n *IndexExpr) Pos() lsp.Position { return n.X.Pos() }
+func (n *IndexExpr) End() lsp.Position { return n.Stop }
+func (n *MemberExpr) Pos() lsp.Position { return n.X.Pos() }
+func (n *MemberExpr) End() lsp.Position { return n.Member.End() }
+func (n *BadExpr) Pos() lsp.Position { return n.From }
+func (n *BadExpr) End() lsp.Position { return n.To }
+
+func (n *BlockStmt) Pos() lsp.Position { return n.Lbrace }
+func (n *BlockStmt) End() lsp.Position { return n.Stop }
+func (n *ExprStmt) Pos() lsp.Position { return n.X.Pos() }
+func (n *ExprStmt) End() lsp.Position { return n.Stop }
+func (n *EmptyStmt) Pos() lsp.Position { return n.Semicolon }
+func (n *EmptyStmt) End() lsp.Position {
+	return lsp.Position{Line: n.Semicolon.Line, Character: n.Semicolon.Character + 1}
+}
+func (n *IfStmt) Pos() lsp.Position { return n.If }
+func (n *IfStmt) End() lsp.Position {
+	if n.Else != nil {
+		return n.Else.End()
+	}
+	return n.Body.End()
+}
+func (n *WhileStmt) Pos() lsp.Position { return n.While }
+func (n *WhileStmt) End() lsp.Position { return n.Body.End() }
+func (n *ForStmt) Pos() lsp.Position { return n.For }
+func (n *ForStmt) End() lsp.Position { return n.Body.End() }
+func (n *ReturnStmt) Pos() lsp.Position { return n.Return }
+func (n *ReturnStmt) End() lsp.Position { return n.Stop }
+func (n *BranchStmt) Pos() lsp.Position { return n.TokPos }
+func (n *BranchStmt) End() lsp.Position { return n.Stop }
+func (n *BadStmt) Pos() lsp.Position { return n.From }
+func (n *BadStmt) End() lsp.Position { return n.To }
+
+func (*IncludeDirective) declNode() {}
+func (*FuncDecl) declNode() {}
+func (*VarDecl) declNode() {}
+func (*BadDecl) declNode() {}
+
+func (*Ident) exprNode() {}
+func (*BasicLit) exprNode() {}
+func (*CallExpr) exprNode() {}
+func (*CallbackExpr) exprNode() {}
+func (*ParenExpr) exprNode() {}
+func (*UnaryExpr) exprNode() {}
+func (*BinaryExpr) exprNode() {}
+func (*AssignExpr) exprNode() {}
+func (*IndexExpr) exprNode() {}
+func (*MemberExpr) exprNode() {}
+func (*BadExpr) exprNode() {}
+
+func (*BlockStmt) stmtNode() {}
+func (*ExprStmt) stmtNode() {}
+func (*EmptyStmt) stmtNode() {}
+func (*IfStmt) stmtNode() {}
+func (*WhileStmt) stmtNode() {}
+func (*ForStmt) stmtNode() {}
+func (*ReturnStmt) stmtNode() {}
+func (*BranchStmt) stmtNode() {}
+func (*BadStmt) stmtNode() {}
+// VarDecl is a statement as well, for local variables.
+func (*VarDecl) stmtNode() {}
+
+// PositionLess reports whether a comes before b.
+func PositionLess(a, b lsp.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// Contains reports whether pos lies within the node, its end included,
+// so a cursor right behind an identifier still counts as on it.
+func Contains(node Node, pos lsp.Position) bool {
+	return !PositionLess(pos, node.Pos()) && !PositionLess(node.End(), pos)
+}
+
+func NodeRange(node Node) lsp.Range {
+	return lsp.Range{Start: node.Pos(), End: node.End()}
+}
+
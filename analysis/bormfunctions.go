@@ -1,28 +1,164 @@
 package analysis
 
 import (
+	"borm-lsp/lsp"
 	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 type BormFunction struct {
 	Group string
 	Namespace string
 	Name string
+	// ReturnType is normalized like TypeName, empty where the catalog has none.
+	ReturnType string
+	Params []BormParam
+	// Definition is the signature as it is shown to the user.
 	Definition string
 	Description string	
 }
 
-func NewBormFunction(group, ns, retval, name, params, desc string) BormFunction {
-	definition := fmt.Sprintf("%s %s(%s) {}", retval, name, params)
-	return BormFunction{
+type BormParam struct {
+	// Type is normalized like TypeName, empty if it could not be read.
+	Type string
+	// Name is empty for parameters the catalog only lists with their type.
+	Name string
+	ByRef bool
+}
+
+// IsConstant reports whether the entry is a constant: a name in capitals without parameters.
+func (f *BormFunction) IsConstant() bool {
+	return len(f.Params) == 0 && f.Name == strings.ToUpper(f.Name) && strings.ToLower(f.Name) != f.Name
+}
+
+func (p BormParam) String() string {
+	text := p.Type
+	if p.ByRef {
+		text += " &" + p.Name
+	} else if p.Name != "" {
+		text += " " + p.Name
+	}
+	return text
+}
+
+// ParseBormFunction builds a catalog entry, reading the return type and parameters.
+// Parts it cannot read are left unknown and reported in the error, the entry is usable anyway.
+func ParseBormFunction(group, ns, retval, name, params, desc string) (BormFunction, error) {
+	function := BormFunction{
 		Group: group,
 		Namespace: ns,
 		Name: name,
-		Definition: definition,
+		Params: []BormParam{},
 		Description: desc,
 	}
+	problems := []string{}
+
+	if retval = strings.TrimSpace(retval); retval != "" {
+		function.ReturnType = normalizeType(retval)
+		if function.ReturnType == "" {
+			problems = append(problems, fmt.Sprintf("cannot read return type '%s'", retval))
+		}
+	}
+	if params = strings.TrimSpace(params); params != "" {
+		for i, text := range splitTypeArgs(params) {
+			param, ok := parseBormParam(text)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("cannot read parameter %d '%s'", i+1, strings.TrimSpace(text)))
+			}
+			function.Params = append(function.Params, param)
+		}
+	}
+
+	signature := []string{}
+	for _, param := range function.Params {
+		signature = append(signature, param.String())
+	}
+	function.Definition = fmt.Sprintf("%s %s(%s) {}", function.ReturnType, name, strings.Join(signature, ", "))
+
+	if len(problems) > 0 {
+		return function, fmt.Errorf("%s: %s", name, strings.Join(problems, ", "))
+	}
+	return function, nil
+}
+
+// Declaration is the entry written as a script declares it: a global variable for a constant,
+// a function without a body otherwise. Types the catalog does not give are declared variant,
+// as are callbacks, which have no type a script could write.
+func (f *BormFunction) Declaration() string {
+	result := f.ReturnType
+	if result == TYPE_CALLBACK {
+		result = TYPE_VARIANT
+	}
+	if result == "" && f.IsConstant() {
+		result = TYPE_VARIANT
+	} else if result == "" {
+		result = TYPE_VOID
+	}
+	if f.IsConstant() {
+		return fmt.Sprintf("%s %s;", result, f.Name)
+	}
+	params := []string{}
+	for i, param := range f.Params {
+		if param.Type == "" || param.Type == TYPE_CALLBACK {
+			param.Type = TYPE_VARIANT
+		}
+		if param.Name == "" {
+			param.Name = fmt.Sprintf("p%d", i+1)
+		}
+		params = append(params, param.String())
+	}
+	return fmt.Sprintf("%s function %s(%s);", result, f.Name, strings.Join(params, ", "))
+}
+
+// NewBormFunction is ParseBormFunction for entries known to be well formed.
+func NewBormFunction(group, ns, retval, name, params, desc string) BormFunction {
+	function, _ := ParseBormFunction(group, ns, retval, name, params, desc)
+	return function
+}
+
+// normalizeType reads a type written in the catalog, empty if it is no type.
+func normalizeType(text string) string {
+	p := NewParser(text)
+	if p.accept(TOKEN_KEYWORD, "callback") && p.atEOF() {
+		return TYPE_CALLBACK
+	}
+	p.pos = 0
+	typ := p.parseType()
+	if typ == nil || !p.atEOF() {
+		return ""
+	}
+	return TypeName(typ)
+}
+
+// parseBormParam reads `type name`, `type &name` or just `type`.
+func parseBormParam(text string) (BormParam, bool) {
+	p := NewParser(text)
+	var typ *TypeExpr
+	if p.at(TOKEN_KEYWORD, "callback") {
+		typ = &TypeExpr{Name: p.next().Value}
+	} else {
+		typ = p.parseType()
+	}
+	param := BormParam{Type: TypeName(typ)}
+	param.ByRef = p.accept(TOKEN_OPERATOR, "&")
+	if p.peek().Kind == TOKEN_IDENT {
+		param.Name = p.next().Value
+	}
+	if typ == nil || !p.atEOF() || len(p.lexErrors) > 0 {
+		// keep whatever looks like the name, the type stays unknown
+		param.Type = ""
+		fields := strings.Fields(strings.Trim(text, " &)"))
+		if len(fields) > 0 {
+			param.Name = strings.TrimLeft(fields[len(fields)-1], "&")
+		}
+		return param, false
+	}
+	return param, true
 }
 
 func FindFunctionByName(functions []BormFunction, name string) (*BormFunction, bool) {
@@ -64,22 +200,43 @@ func FindFunctionsByNamespace(functions []BormFunction, ns string) []BormFunctio
 	return nsFuncs
 }
 
-func ReadFunctionsFromFile(filename string) ([]BormFunction, error) {
+// CatalogError is a catalog entry that could not be read completely.
+type CatalogError struct {
+	Line int
+	Err error
+}
+
+func (e CatalogError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// ReadFunctionsFromFile loads the function catalog. The file is decoded with DecodeText,
+// the catalog is exported as Windows-1252.
+func ReadFunctionsFromFile(filename string) ([]BormFunction, []CatalogError, error) {
 	functions := []BormFunction{}
-	file, err := os.Open(filename)
+	malformed := []CatalogError{}
+	text, err := ReadTextFile(filename)
 	if err != nil {
-		return functions, err
+		return functions, malformed, err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return functions, err
+	reader := csv.NewReader(strings.NewReader(text))
+	// the header names the columns
+	if _, err := reader.Read(); err != nil {
+		return functions, malformed, err
 	}
 
 	var group, ns string
-	for _, record := range records {
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return functions, malformed, err
+		}
+		line, _ := reader.FieldPos(0)
+
 		if record[0] != "" {
 			group = record[0]
 		}
@@ -87,8 +244,142 @@ func ReadFunctionsFromFile(filename string) ([]BormFunction, error) {
 			ns = record[1]
 		}
 		retval, name, params, desc := record[2], record[3], record[4], record[5]
-		functions = append(functions, NewBormFunction(group, ns, retval, name, params, desc))
+		function, err := ParseBormFunction(group, ns, retval, name, params, desc)
+		if err != nil {
+			malformed = append(malformed, CatalogError{Line: line, Err: err})
+		}
+		functions = append(functions, function)
+	}
+
+	return functions, malformed, nil
+}
+
+// Catalog is the set of functions and constants the BORM runtime provides.
+type Catalog struct {
+	Functions []BormFunction
+	names map[string]int
+	// declarations is the file DeclarationFile wrote, lines where it declares each name.
+	declarations string
+	lines map[string]int
+	// types is filled by TypeNames.
+	types []string
+}
+
+func NewCatalog(functions []BormFunction) *Catalog {
+	c := &Catalog{Functions: functions, names: map[string]int{}}
+	for i, function := range functions {
+		if _, ok := c.names[function.Name]; !ok {
+			c.names[function.Name] = i
+		}
+	}
+	return c
+}
+
+// Lookup finds a function by name, the first entry wins when a name is listed twice.
+func (c *Catalog) Lookup(name string) (*BormFunction, bool) {
+	if c == nil {
+		return nil, false
+	}
+	i, ok := c.names[name]
+	if !ok {
+		return nil, false
+	}
+	return &c.Functions[i], true
+}
+
+// TypeNames are the class types the signatures of the catalog use, like Json, sorted.
+func (c *Catalog) TypeNames() []string {
+	if c == nil {
+		return []string{}
+	}
+	if c.types != nil {
+		return c.types
 	}
+	seen := map[string]bool{}
+	add := func(typ string) {
+		// type arguments and array elements are types of their own
+		for _, name := range strings.FieldsFunc(typ, func(r rune) bool { return r >= 0x80 || !isIdentPart(byte(r)) }) {
+			if isIdentifier(name) && !IsBuiltinType(strings.ToLower(name)) && !strings.EqualFold(name, TYPE_CALLBACK) {
+				seen[name] = true
+			}
+		}
+	}
+	for _, function := range c.Functions {
+		add(function.ReturnType)
+		for _, param := range function.Params {
+			add(param.Type)
+		}
+	}
+	c.types = []string{}
+	for name := range seen {
+		c.types = append(c.types, name)
+	}
+	sort.Strings(c.types)
+	return c.types
+}
 
-	return functions, nil
+// CATALOG_HEADER starts the file DeclarationFile writes. The parser accepts the functions
+// without a body it declares only in a file starting with it.
+const CATALOG_HEADER = "// Declarations of the function catalog, generated by bormlsp."
+
+// DeclarationFile writes a script into dir that declares every entry of the catalog, with its
+// description as a comment, so a builtin has a declaration to go to. It is written again
+// only when it was deleted.
+func (c *Catalog) DeclarationFile(dir string) (string, error) {
+	if c.declarations != "" {
+		if _, err := os.Stat(c.declarations); err == nil {
+			return c.declarations, nil
+		}
+	}
+	var text strings.Builder
+	lines := map[string]int{}
+	line := 0
+	writeLine := func(s string) {
+		text.WriteString(s)
+		text.WriteString("\n")
+		line++
+	}
+	writeLine(CATALOG_HEADER)
+	for i, function := range c.Functions {
+		if c.names[function.Name] != i {
+			continue
+		}
+		writeLine("")
+		writeLine(fmt.Sprintf("// %s, %s", function.Group, function.Namespace))
+		for _, description := range strings.Split(strings.TrimSpace(function.Description), "\n") {
+			if description = strings.TrimRight(description, "\r"); description != "" {
+				writeLine("// " + description)
+			}
+		}
+		lines[function.Name] = line
+		writeLine(function.Declaration())
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "catalog.sct")
+	if err := os.WriteFile(path, []byte(text.String()), 0644); err != nil {
+		return "", err
+	}
+	c.declarations = path
+	c.lines = lines
+	return path, nil
+}
+
+// Declaration is the location of a builtin in the file DeclarationFile writes.
+func (c *Catalog) Declaration(dir, name string) (lsp.Location, error) {
+	function, ok := c.Lookup(name)
+	if !ok {
+		return lsp.Location{}, fmt.Errorf("'%s' is not in the catalog", name)
+	}
+	path, err := c.DeclarationFile(dir)
+	if err != nil {
+		return lsp.Location{}, err
+	}
+	start := strings.Index(function.Declaration(), " "+function.Name) + 1
+	return lsp.Location{
+		URI: PathToURI(path),
+		Range: LineRange(c.lines[name], start, start+len(function.Name)),
+	}, nil
 }
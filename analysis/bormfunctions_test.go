@@ -0,0 +1,95 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"os"
+	"testing"
+)
+
+func TestParseBormFunction(t *testing.T) {
+	function, err := analysis.ParseBormFunction("String", "Programm", "void", "__StringToArray",
+		"string s,String __Delimiter,map<string,long> &mpStringToArray,callback _p_FunctionName, long", "")
+	if err != nil {
+		t.Fatalf("Expected: no error, Actual: %s", err)
+	}
+	expected := []analysis.BormParam{
+		{Type: "string", Name: "s"},
+		{Type: "string", Name: "__Delimiter"},
+		{Type: "map<string,long>", Name: "mpStringToArray", ByRef: true},
+		{Type: "callback", Name: "_p_FunctionName"},
+		{Type: "long"},
+	}
+	if function.ReturnType != "void" || len(function.Params) != len(expected) {
+		t.Fatalf("Expected: void with %d params, Actual: %s with %v", len(expected), function.ReturnType, function.Params)
+	}
+	for i, param := range function.Params {
+		if param != expected[i] {
+			t.Fatalf("Expected: %v, Actual: %v", expected[i], param)
+		}
+	}
+	definition := "void __StringToArray(string s, string __Delimiter, map<string,long> &mpStringToArray, callback _p_FunctionName, long) {}"
+	if function.Definition != definition {
+		t.Fatalf("Expected: %s, Actual: %s", definition, function.Definition)
+	}
+}
+
+func TestParseMalformedBormFunction(t *testing.T) {
+	function, err := analysis.ParseBormFunction("Dialog", "Programm", "", "AppendRibbonBarDropDown",
+		"string __title,string __callbackParameter)", "")
+	if err == nil {
+		t.Fatalf("Expected: an error for the stray parenthesis")
+	}
+	if len(function.Params) != 2 || function.Params[1].Type != "" || function.Params[1].Name != "__callbackParameter" {
+		t.Fatalf("Expected: second parameter without type, Actual: %v", function.Params)
+	}
+}
+
+func TestReadCatalog(t *testing.T) {
+	functions, malformed, err := analysis.ReadFunctionsFromFile("../bormfuncs.csv")
+	if err != nil {
+		t.Fatalf("Expected: no error, Actual: %s", err)
+	}
+	if functions[0].Name == "Name" {
+		t.Fatalf("Expected: header row skipped")
+	}
+	if len(malformed) != 1 || malformed[0].Line != 3110 {
+		t.Fatalf("Expected: one malformed entry, Actual: %v", malformed)
+	}
+}
+
+func TestDeclarationFile(t *testing.T) {
+	functions, _, err := analysis.ReadFunctionsFromFile("../bormfuncs.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := analysis.NewCatalog(functions)
+	dir := t.TempDir()
+	path, err := catalog.DeclarationFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := analysis.ReadTextFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := analysis.NewState()
+	state.Catalog = catalog
+	state.Initialize(logger, lsp.InitializeRequestParams{})
+	all := state.OpenDocument(logger, lsp.TextDocumentItem{URI: analysis.PathToURI(path), Version: 1, Text: text})
+	if len(all[0].Diagnostics) != 0 {
+		t.Fatalf("Expected: no diagnostics, Actual: %v", all[0].Diagnostics)
+	}
+
+	// a deleted file is written again
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if location, err := catalog.Declaration(dir, "MsgBox"); err != nil || analysis.URIToPath(location.URI) != path {
+		t.Fatalf("Expected: MsgBox in %s, Actual: %v %v", path, location, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected: %s written again, Actual: %v", path, err)
+	}
+}
@@ -0,0 +1,189 @@
+package analysis
+
+// BasicBlock is a run of nodes that execute one after the other. Nodes are simple statements,
+// the conditions of if, while and for, and the init and post statements of for loops.
+type BasicBlock struct {
+	Index int
+	Nodes []Node
+	Succs []*BasicBlock
+	Preds []*BasicBlock
+}
+
+// CFG is the control-flow graph of a function body. Exit is reached by every return and,
+// if the body can fall off its end, from End.
+type CFG struct {
+	Entry *BasicBlock
+	Exit *BasicBlock
+	// End is the block the body falls off from, it only reaches Exit if it is reachable.
+	End *BasicBlock
+	Blocks []*BasicBlock
+	// Starts maps every statement to the block it starts in.
+	Starts map[Stmt]*BasicBlock
+}
+
+// NewCFG builds the graph of a function with a body. Statements after a return, break or
+// continue start a block without predecessors. Loops with a missing or `true` condition
+// are only left through break.
+func NewCFG(function *FuncDecl) *CFG {
+	b := &cfgBuilder{cfg: &CFG{Starts: map[Stmt]*BasicBlock{}}}
+	b.cfg.Entry = b.block()
+	b.cfg.Exit = b.block()
+	b.current = b.cfg.Entry
+	b.stmt(function.Body)
+	b.cfg.End = b.current
+	b.edge(b.current, b.cfg.Exit)
+	return b.cfg
+}
+
+// Reachable returns the blocks that can be reached from Entry.
+func (g *CFG) Reachable() map[*BasicBlock]bool {
+	reachable := map[*BasicBlock]bool{}
+	var visit func(block *BasicBlock)
+	visit = func(block *BasicBlock) {
+		if reachable[block] {
+			return
+		}
+		reachable[block] = true
+		for _, succ := range block.Succs {
+			visit(succ)
+		}
+	}
+	visit(g.Entry)
+	return reachable
+}
+
+type loopTargets struct {
+	breakTo *BasicBlock
+	continueTo *BasicBlock
+}
+
+type cfgBuilder struct {
+	cfg *CFG
+	current *BasicBlock
+	loops []loopTargets
+}
+
+func (b *cfgBuilder) block() *BasicBlock {
+	block := &BasicBlock{Index: len(b.cfg.Blocks)}
+	b.cfg.Blocks = append(b.cfg.Blocks, block)
+	return block
+}
+
+func (b *cfgBuilder) edge(from, to *BasicBlock) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+func (b *cfgBuilder) add(node Node) {
+	b.current.Nodes = append(b.current.Nodes, node)
+}
+
+// jump ends the current block with an edge to target, what follows is unreachable from here.
+func (b *cfgBuilder) jump(target *BasicBlock) {
+	b.edge(b.current, target)
+	b.current = b.block()
+}
+
+func (b *cfgBuilder) stmt(stmt Stmt) {
+	if stmt == nil {
+		return
+	}
+	b.cfg.Starts[stmt] = b.current
+	switch s := stmt.(type) {
+	case *BlockStmt:
+		for _, stmt := range s.List {
+			b.stmt(stmt)
+		}
+	case *ReturnStmt:
+		b.add(s)
+		b.jump(b.cfg.Exit)
+	case *BranchStmt:
+		b.add(s)
+		if len(b.loops) == 0 {
+			return
+		}
+		loop := b.loops[len(b.loops)-1]
+		if s.Tok == "break" {
+			b.jump(loop.breakTo)
+		} else {
+			b.jump(loop.continueTo)
+		}
+	case *IfStmt:
+		if s.Cond != nil {
+			b.add(s.Cond)
+		}
+		cond := b.current
+		after := b.block()
+		b.current = b.block()
+		b.edge(cond, b.current)
+		b.stmt(s.Body)
+		b.edge(b.current, after)
+		if s.Else != nil {
+			b.current = b.block()
+			b.edge(cond, b.current)
+			b.stmt(s.Else)
+			b.edge(b.current, after)
+		} else {
+			b.edge(cond, after)
+		}
+		b.current = after
+	case *WhileStmt:
+		head := b.block()
+		b.edge(b.current, head)
+		b.current = head
+		b.loop(s.Cond, s.Body, head, nil)
+	case *ForStmt:
+		b.stmt(s.Init)
+		head := b.block()
+		b.edge(b.current, head)
+		b.current = head
+		b.loop(s.Cond, s.Body, head, s.Post)
+	default:
+		b.add(s)
+	}
+}
+
+// loop builds a loop whose condition is evaluated in head, the current block.
+func (b *cfgBuilder) loop(cond Expr, body Stmt, head *BasicBlock, post Stmt) {
+	if cond != nil {
+		b.add(cond)
+	}
+	after := b.block()
+	if !isTrue(cond) {
+		b.edge(head, after)
+	}
+	next := head
+	if post != nil {
+		next = b.block()
+	}
+
+	b.current = b.block()
+	b.edge(head, b.current)
+	b.loops = append(b.loops, loopTargets{breakTo: after, continueTo: next})
+	b.stmt(body)
+	b.loops = b.loops[:len(b.loops)-1]
+	b.edge(b.current, next)
+
+	if post != nil {
+		b.current = next
+		b.stmt(post)
+		b.edge(b.current, head)
+	}
+	b.current = after
+}
+
+// isTrue reports whether a loop condition always holds, `for (;;)` and `while (true)`.
+func isTrue(cond Expr) bool {
+	for {
+		paren, ok := cond.(*ParenExpr)
+		if !ok {
+			break
+		}
+		cond = paren.X
+	}
+	if cond == nil {
+		return true
+	}
+	lit, ok := cond.(*BasicLit)
+	return ok && lit.Kind == TOKEN_KEYWORD && lit.Value == "true"
+}
@@ -0,0 +1,70 @@
+package analysis
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// cp1252 maps the bytes 0x80 to 0x9F of Windows-1252 to unicode. All other bytes are
+// the same as in Latin-1. The five bytes Windows leaves undefined map to the C1 controls.
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// DecodeText turns the content of a file into UTF-8. A byte order mark decides between
+// UTF-8 and UTF-16, without one the text is taken as UTF-8 if it is valid and as
+// Windows-1252 otherwise, which is what the BORM tools write.
+func DecodeText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return strings.ToValidUTF8(string(data[3:]), "�")
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true)
+	case utf8.Valid(data):
+		return string(data)
+	}
+	return decodeCP1252(data)
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+func decodeCP1252(data []byte) string {
+	sb := strings.Builder{}
+	sb.Grow(len(data) + len(data)/8)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			sb.WriteByte(b)
+		case b < 0xA0:
+			sb.WriteRune(cp1252[b-0x80])
+		default:
+			sb.WriteRune(rune(b))
+		}
+	}
+	return sb.String()
+}
+
+// ReadTextFile reads a file from disk and decodes it with DecodeText.
+func ReadTextFile(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return DecodeText(data), nil
+}
@@ -0,0 +1,35 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	cases := []struct {
+		data []byte
+		expected string
+	}{
+		{[]byte("R\xfcckgabetyp \x84Pr\xfcfung\x93 \x80"), "Rückgabetyp „Prüfung“ €"},
+		{[]byte("Rückgabetyp"), "Rückgabetyp"},
+		{[]byte("\xef\xbb\xbfRückgabetyp"), "Rückgabetyp"},
+		{[]byte("\xff\xfeR\x00\xfc\x00c\x00k\x00"), "Rück"},
+		{[]byte("\xfe\xff\x00R\x00\xfc\x00c\x00k"), "Rück"},
+	}
+	for _, c := range cases {
+		if actual := analysis.DecodeText(c.data); actual != c.expected {
+			t.Fatalf("Expected: %q, Actual: %q", c.expected, actual)
+		}
+	}
+}
+
+func TestReadCatalogAsUTF8(t *testing.T) {
+	functions, _, err := analysis.ReadFunctionsFromFile("../bormfuncs.csv")
+	if err != nil {
+		t.Fatalf("Expected: no error, Actual: %s", err)
+	}
+	function, found := analysis.FindFunctionByName(functions, "GetActiveWindow")
+	if !found || function.Description != "Gibt das aktuelle Windows-Fenster zurück" {
+		t.Fatalf("Expected: GetActiveWindow with umlauts, Actual: %v", function)
+	}
+}
@@ -0,0 +1,231 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// completionContext is what the text before the cursor tells about the name being typed.
+type completionContext struct {
+	// prefix is the part of the name already typed, start is where it begins.
+	prefix string
+	start int
+	// statement is set where a statement or a declaration can begin, declaration where only a
+	// type can follow, in the parameters of a function declaration.
+	statement bool
+	declaration bool
+}
+
+// completionContextAt reads the context at offset. It fails inside comments, strings and
+// directives, after a member access and where a new name is being declared.
+func completionContextAt(text string, offset int) (completionContext, bool) {
+	offset = min(max(offset, 0), len(text))
+	start := offset
+	for start > 0 && isIdentPart(text[start-1]) {
+		start--
+	}
+	context := completionContext{prefix: text[start:offset], start: start}
+	if context.prefix != "" && !isIdentStart(context.prefix[0]) {
+		return context, false
+	}
+
+	tokens := []Token{}
+	for _, token := range Tokenize(text[:start]) {
+		if token.EndOffset() == start && !closed(token) {
+			// the name is typed inside this comment, string or directive
+			return context, false
+		}
+		if token.Kind != TOKEN_COMMENT {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == 0 {
+		context.statement = true
+		return context, true
+	}
+	previous := tokens[len(tokens)-1]
+	switch {
+	case previous.Kind == TOKEN_IDENT, previous.Kind == TOKEN_KEYWORD && previous.Value == "function":
+		// the name of a new variable, parameter or function
+		return context, false
+	case previous.Is(TOKEN_PUNCT, "."):
+		return context, false
+	case previous.Is(TOKEN_PUNCT, "{"), previous.Is(TOKEN_PUNCT, "}"), previous.Is(TOKEN_PUNCT, ";"):
+		context.statement = true
+	case previous.Is(TOKEN_KEYWORD, "else"), previous.Is(TOKEN_PUNCT, ")") && statementHead(tokens):
+		context.statement = true
+	case previous.Is(TOKEN_PUNCT, "(") && len(tokens) > 1 && tokens[len(tokens)-2].Is(TOKEN_KEYWORD, "for"):
+		// the initialization of a for loop may declare its variable
+		context.statement = true
+	case previous.Is(TOKEN_PUNCT, "("), previous.Is(TOKEN_PUNCT, ","):
+		if name, _, ok := CallAt(text, start); ok {
+			for i, token := range tokens {
+				if token.Offset == name.Offset {
+					context.declaration = i > 0 && tokens[i-1].Is(TOKEN_KEYWORD, "function")
+				}
+			}
+		}
+	}
+	return context, true
+}
+
+// closed reports whether a comment, string or directive token ends before the text after it.
+func closed(token Token) bool {
+	switch token.Kind {
+	case TOKEN_COMMENT:
+		return strings.HasPrefix(token.Value, "/*") && len(token.Value) >= 4 && strings.HasSuffix(token.Value, "*/")
+	case TOKEN_STRING:
+		return len(token.Value) >= 2 && strings.HasSuffix(token.Value, "\"") && !strings.HasSuffix(token.Value, "\\\"")
+	case TOKEN_DIRECTIVE:
+		return false
+	}
+	return true
+}
+
+// statementHead reports whether the tokens end with the condition of an if, while or for,
+// so a statement follows.
+func statementHead(tokens []Token) bool {
+	depth := 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch {
+		case tokens[i].Is(TOKEN_PUNCT, ")"):
+			depth++
+		case tokens[i].Is(TOKEN_PUNCT, "("):
+			depth--
+			if depth == 0 {
+				return i > 0 && tokens[i-1].Kind == TOKEN_KEYWORD
+			}
+		}
+	}
+	return false
+}
+
+// Completion suggests the names that fit where the cursor is: names in scope, functions and
+// globals of the workspace and catalog entries in expressions, keywords and types where a
+// statement begins and only types in the parameters of a function declaration.
+func (s *State) Completion(id int, uri string, position lsp.Position) lsp.CompletionResponse {
+	response := lsp.CompletionResponse{Response: lsp.Response{RPC: "2.0", Id: &id}, Result: []lsp.CompletionItem{}}
+	document, ok := s.Documents[NormalizeURI(uri)]
+	if !ok {
+		return response
+	}
+	context, ok := completionContextAt(document.Text, document.Offset(document.FromClient(position)))
+	if !ok {
+		return response
+	}
+	prefix := strings.ToLower(context.prefix)
+	seen := map[string]bool{}
+	add := func(item lsp.CompletionItem) {
+		if !seen[item.Label] && strings.HasPrefix(strings.ToLower(item.Label), prefix) {
+			seen[item.Label] = true
+			response.Result = append(response.Result, item)
+		}
+	}
+
+	if context.statement || context.declaration {
+		types := []string{}
+		for name := range builtinTypes {
+			types = append(types, name)
+		}
+		sort.Strings(types)
+		// class types come from the catalog and the scripts using them
+		types = append(types, s.Catalog.TypeNames()...)
+		types = append(types, s.Workspace.TypeNames()...)
+		for _, name := range types {
+			add(lsp.CompletionItem{Label: name, Kind: lsp.COMPLETION_KIND_CLASS, Detail: "type"})
+		}
+		add(lsp.CompletionItem{Label: "callback", Kind: lsp.COMPLETION_KIND_CLASS, Detail: "type"})
+	}
+	if context.declaration {
+		return response
+	}
+
+	pos := document.Position(context.start)
+	for scope := document.Resolution.ScopeAt(document.Tree.Path(pos)); scope != nil; scope = scope.Parent {
+		names := []string{}
+		for name := range scope.Symbols {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if symbol := scope.LookupAt(name, pos); symbol != nil && symbol.Ident != nil {
+				add(symbolCompletion(document.Tree.File, symbol, ""))
+			}
+		}
+	}
+	for _, uri := range s.Workspace.uris() {
+		file := s.Workspace.Files[uri]
+		names := []string{}
+		for name := range file.Resolution.Root.Symbols {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if symbol := file.Resolution.Root.Symbols[name]; symbol.Ident != nil {
+				add(symbolCompletion(file.Tree.File, symbol, filepath.Base(URIToPath(uri))))
+			}
+		}
+	}
+	if s.Catalog != nil {
+		for _, function := range s.Catalog.Functions {
+			add(builtinCompletion(&function))
+		}
+	}
+
+	keywords := []string{"true", "false"}
+	if context.statement {
+		keywords = []string{"if", "else", "while", "for", "return", "break", "continue", "function", "true", "false"}
+	}
+	for _, keyword := range keywords {
+		add(lsp.CompletionItem{Label: keyword, Kind: lsp.COMPLETION_KIND_KEYWORD, Detail: "keyword"})
+	}
+	return response
+}
+
+// symbolCompletion is the item of a function, global, parameter or local, file names the
+// file declaring it when that is another one.
+func symbolCompletion(file *File, symbol *Symbol, from string) lsp.CompletionItem {
+	item := lsp.CompletionItem{Label: symbol.Name, Kind: lsp.COMPLETION_KIND_VARIABLE, Detail: TypeName(symbol.Type)}
+	if function, ok := symbol.Decl.(*FuncDecl); ok {
+		item.Kind = lsp.COMPLETION_KIND_FUNCTION
+		item.Detail = FuncSignature(function).String()
+		item.Documentation = docComment(file, function)
+	}
+	if from != "" {
+		item.Detail += " (" + from + ")"
+	}
+	return item
+}
+
+// builtinCompletion is the item of a catalog entry, its description is added by ResolveCompletion.
+func builtinCompletion(function *BormFunction) lsp.CompletionItem {
+	item := lsp.CompletionItem{
+		Label: function.Name,
+		Kind: lsp.COMPLETION_KIND_FUNCTION,
+		Detail: BuiltinSignature(function).String(),
+		Data: &lsp.CompletionItemData{Builtin: function.Name},
+	}
+	if function.IsConstant() {
+		item.Kind = lsp.COMPLETION_KIND_CONSTANT
+		item.Detail = strings.TrimSpace(function.ReturnType + " " + function.Name)
+	}
+	return item
+}
+
+// ResolveCompletion adds the description of a catalog entry to its item.
+func (s *State) ResolveCompletion(id int, item lsp.CompletionItem) lsp.CompletionItemResolveResponse {
+	if item.Data != nil {
+		if function, ok := s.Catalog.Lookup(item.Data.Builtin); ok {
+			item.Documentation = strings.TrimSpace(function.Description)
+		}
+	}
+	return lsp.CompletionItemResolveResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id: &id,
+		},
+		Result: item,
+	}
+}
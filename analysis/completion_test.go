@@ -0,0 +1,92 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "// Counts the rows.\nlong function CountRows(string table) {\n\treturn 0;\n}\n",
+		"grid.sct": "void function Place(GridCellPosition cell) {\n}\n",
+	},
+		analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text, string title", "Zeigt eine Meldung an"),
+		analysis.NewBormFunction("Global", "Programm", "long", "MB_OK", "", "Nur eine OK-Schaltfläche"),
+		analysis.NewBormFunction("Json", "Programm", "Json", "JsonParse", "string text", ""),
+	)
+	main := scriptURI(state, "main.sct")
+	text := "#include \"lib.sct\"\n" +
+		"long counter;\n" +
+		"void function main(string title) {\n" +
+		"\tlong count = 1;\n" +
+		"\tco;\n" +
+		"\tMsgBox(M, \"// co\");\n" +
+		"\tlong later = 2;\n" +
+		"}\n" +
+		"long function Other(lo) {\n" +
+		"\treturn 0;\n" +
+		"}\n"
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+
+	complete := func(line, character int) map[string]lsp.CompletionItem {
+		items := map[string]lsp.CompletionItem{}
+		for _, item := range state.Completion(1, main, lsp.Position{Line: line, Character: character}).Result {
+			items[item.Label] = item
+		}
+		return items
+	}
+	expect := func(items map[string]lsp.CompletionItem, labels map[string]int) {
+		t.Helper()
+		if len(items) != len(labels) {
+			t.Fatalf("Expected: %v, Actual: %v", labels, items)
+		}
+		for label, kind := range labels {
+			if item, ok := items[label]; !ok || item.Kind != kind {
+				t.Fatalf("Expected: %s of kind %d, Actual: %v", label, kind, items)
+			}
+		}
+	}
+
+	statement := complete(4, 3)
+	expect(statement, map[string]int{
+		"count": lsp.COMPLETION_KIND_VARIABLE,
+		"counter": lsp.COMPLETION_KIND_VARIABLE,
+		"CountRows": lsp.COMPLETION_KIND_FUNCTION,
+		"continue": lsp.COMPLETION_KIND_KEYWORD,
+	})
+	if rows := statement["CountRows"]; rows.Detail != "long CountRows(string table) (lib.sct)" || rows.Documentation != "Counts the rows." {
+		t.Fatalf("Expected: CountRows of lib.sct, Actual: %v", rows)
+	}
+
+	argument := complete(5, 9)
+	expect(argument, map[string]int{
+		"MsgBox": lsp.COMPLETION_KIND_FUNCTION,
+		"MB_OK": lsp.COMPLETION_KIND_CONSTANT,
+		"main": lsp.COMPLETION_KIND_FUNCTION,
+	})
+	msgBox := argument["MsgBox"]
+	if msgBox.Documentation != "" || msgBox.Detail != "void MsgBox(string text, string title)" {
+		t.Fatalf("Expected: MsgBox without documentation, Actual: %v", msgBox)
+	}
+	if resolved := state.ResolveCompletion(2, msgBox).Result; resolved.Documentation != "Zeigt eine Meldung an" {
+		t.Fatalf("Expected: the description of MsgBox, Actual: %v", resolved)
+	}
+
+	expect(complete(5, 16), map[string]int{})
+	expect(complete(3, 11), map[string]int{})
+	expect(complete(8, 22), map[string]int{"long": lsp.COMPLETION_KIND_CLASS})
+
+	// class types of the catalog and of the workspace
+	types := scriptURI(state, "types.sct")
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: types, Version: 1, Text: "void function F(J) {\n\tGr\n}\n"})
+	typed := func(line, character int) map[string]lsp.CompletionItem {
+		items := map[string]lsp.CompletionItem{}
+		for _, item := range state.Completion(1, types, lsp.Position{Line: line, Character: character}).Result {
+			items[item.Label] = item
+		}
+		return items
+	}
+	expect(typed(0, 17), map[string]int{"Json": lsp.COMPLETION_KIND_CLASS})
+	expect(typed(1, 3), map[string]int{"GridCellPosition": lsp.COMPLETION_KIND_CLASS})
+}
@@ -0,0 +1,206 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"strings"
+)
+
+type TriviaKind int
+
+const (
+	TRIVIA_WHITESPACE TriviaKind = iota
+	TRIVIA_NEWLINE
+	TRIVIA_COMMENT
+)
+
+// Trivia is source text without meaning to the parser: spaces, line breaks and comments.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+	Start lsp.Position
+}
+
+// CSTToken is a token together with the trivia in front of it.
+type CSTToken struct {
+	Token Token
+	Leading []Trivia
+}
+
+// CSTElement is either a node or a token, exactly one of both is set.
+type CSTElement struct {
+	Node *CSTNode
+	Token *CSTToken
+}
+
+// CSTNode mirrors one AST node and holds every token the node was parsed from,
+// keywords and punctuation included, interleaved with the nodes of its children.
+type CSTNode struct {
+	AST Node
+	Elements []CSTElement
+}
+
+// CST is the lossless concrete syntax tree of a file. Printing it gives back the source byte for byte.
+type CST struct {
+	Root *CSTNode
+	// EOF carries the trivia at the end of the file.
+	EOF *CSTToken
+}
+
+// NewCST lays the tokens of text, trivia included, over the AST parsed from the same text.
+// Tokens not covered by any child node, like skipped garbage, stay with the enclosing node.
+func NewCST(text string, tree *Tree) *CST {
+	b := cstBuilder{tree: tree, tokens: triviaTokens(text)}
+	root := b.build(tree.File)
+	return &CST{Root: root, EOF: b.tokens[len(b.tokens)-1]}
+}
+
+// triviaTokens lexes text and attaches whitespace and comments to the following token.
+// The last token is always EOF.
+func triviaTokens(text string) []*CSTToken {
+	tokens := []*CSTToken{}
+	leading := []Trivia{}
+	lexer := NewLexer(text)
+	offset := 0
+	for {
+		lineStart := lexer.lineStart
+		line := lexer.line
+		token := lexer.Next()
+		leading = append(leading, whitespaceTrivia(text[offset:token.Offset], line, offset-lineStart)...)
+		offset = token.EndOffset()
+
+		if token.IsComment() {
+			leading = append(leading, Trivia{Kind: TRIVIA_COMMENT, Text: token.Value, Start: token.Start})
+			continue
+		}
+		tokens = append(tokens, &CSTToken{Token: token, Leading: leading})
+		leading = []Trivia{}
+		if token.Kind == TOKEN_EOF {
+			return tokens
+		}
+	}
+}
+
+// whitespaceTrivia splits a run of whitespace into line breaks and the blanks between them.
+func whitespaceTrivia(text string, line, character int) []Trivia {
+	trivia := []Trivia{}
+	for len(text) > 0 {
+		start := lsp.Position{Line: line, Character: character}
+		size := 1
+		kind := TRIVIA_NEWLINE
+		switch {
+		case strings.HasPrefix(text, "\r\n"):
+			size = 2
+		case text[0] == '\n' || text[0] == '\r':
+		default:
+			kind = TRIVIA_WHITESPACE
+			size = strings.IndexAny(text, "\r\n")
+			if size < 0 {
+				size = len(text)
+			}
+		}
+		trivia = append(trivia, Trivia{Kind: kind, Text: text[:size], Start: start})
+		if kind == TRIVIA_NEWLINE {
+			line++
+			character = 0
+		} else {
+			character += size
+		}
+		text = text[size:]
+	}
+	return trivia
+}
+
+type cstBuilder struct {
+	tree *Tree
+	tokens []*CSTToken
+	pos int
+}
+
+func (b *cstBuilder) atEOF() bool {
+	return b.tokens[b.pos].Token.Kind == TOKEN_EOF
+}
+
+func (b *cstBuilder) build(node Node) *CSTNode {
+	cst := &CSTNode{AST: node}
+	for _, child := range b.tree.Children(node) {
+		if _, ok := child.(*Comment); ok {
+			// comments are trivia
+			continue
+		}
+		for !b.atEOF() && PositionLess(b.tokens[b.pos].Token.Start, child.Pos()) {
+			cst.Elements = append(cst.Elements, CSTElement{Token: b.tokens[b.pos]})
+			b.pos++
+		}
+		cst.Elements = append(cst.Elements, CSTElement{Node: b.build(child)})
+	}
+	for !b.atEOF() && (node == b.tree.File || !PositionLess(node.End(), b.tokens[b.pos].Token.End)) {
+		cst.Elements = append(cst.Elements, CSTElement{Token: b.tokens[b.pos]})
+		b.pos++
+	}
+	return cst
+}
+
+// Tokens returns the tokens of the node in source order.
+func (n *CSTNode) Tokens() []*CSTToken {
+	tokens := []*CSTToken{}
+	for _, element := range n.Elements {
+		if element.Token != nil {
+			tokens = append(tokens, element.Token)
+		} else {
+			tokens = append(tokens, element.Node.Tokens()...)
+		}
+	}
+	return tokens
+}
+
+// Text returns the source of the node, the trivia in front of its first token excluded.
+func (n *CSTNode) Text() string {
+	sb := strings.Builder{}
+	for i, token := range n.Tokens() {
+		if i > 0 {
+			writeTrivia(&sb, token.Leading)
+		}
+		sb.WriteString(token.Token.Value)
+	}
+	return sb.String()
+}
+
+// FullText returns the source of the node including the trivia in front of it.
+func (n *CSTNode) FullText() string {
+	sb := strings.Builder{}
+	for _, token := range n.Tokens() {
+		writeTrivia(&sb, token.Leading)
+		sb.WriteString(token.Token.Value)
+	}
+	return sb.String()
+}
+
+// Find returns the CST node of an AST node, nil if it is not part of this tree.
+func (n *CSTNode) Find(node Node) *CSTNode {
+	if n.AST == node {
+		return n
+	}
+	for _, element := range n.Elements {
+		if element.Node == nil {
+			continue
+		}
+		if found := element.Node.Find(node); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// String prints the whole file exactly as it was parsed.
+func (c *CST) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(c.Root.FullText())
+	writeTrivia(&sb, c.EOF.Leading)
+	return sb.String()
+}
+
+func writeTrivia(sb *strings.Builder, trivia []Trivia) {
+	for _, t := range trivia {
+		sb.WriteString(t.Text)
+	}
+}
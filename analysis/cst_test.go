@@ -0,0 +1,59 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSTRoundTripsScripts(t *testing.T) {
+	files, err := filepath.Glob("../script/*.sct")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Expected: script files, Actual: %v", err)
+	}
+	for _, filename := range files {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, _ := analysis.Parse(filename, string(content))
+		cst := analysis.NewCST(string(content), analysis.NewTree(file))
+		if cst.String() != string(content) {
+			t.Fatalf("Expected: %s to round trip, Actual:\n%s", filename, cst.String())
+		}
+	}
+}
+
+func TestCSTRoundTripsBrokenSource(t *testing.T) {
+	text := "\t// leading\r\n#include <BD>  // trailing\r\nbool function f(long a {\r\n\tlong x = ;  /* block\r\n comment */ return \"open\r\n}\r\n\r\n   "
+	file, _ := analysis.Parse("test.sct", text)
+	cst := analysis.NewCST(text, analysis.NewTree(file))
+	if cst.String() != text {
+		t.Fatalf("Expected: %q, Actual: %q", text, cst.String())
+	}
+}
+
+func TestCSTNodeText(t *testing.T) {
+	text := "// header\nbool function main() {\n\tMsgBox(\"Hello World!\",   \"HelloWorld.sct\"); // call\n\treturn true;\n}\n"
+	file, _ := analysis.Parse("test.sct", text)
+	cst := analysis.NewCST(text, analysis.NewTree(file))
+
+	function := file.Decls[0].(*analysis.FuncDecl)
+	node := cst.Root.Find(function)
+	if node == nil {
+		t.Fatalf("Expected: a CST node for the function")
+	}
+	expected := text[len("// header\n") : len(text)-1]
+	if node.Text() != expected {
+		t.Fatalf("Expected: %q, Actual: %q", expected, node.Text())
+	}
+	if node.FullText() != text[:len(text)-1] {
+		t.Fatalf("Expected: the header comment as leading trivia, Actual: %q", node.FullText())
+	}
+
+	call := function.Body.List[0].(*analysis.ExprStmt).X
+	if cst.Root.Find(call).Text() != "MsgBox(\"Hello World!\",   \"HelloWorld.sct\")" {
+		t.Fatalf("Expected: the call with its original spacing, Actual: %q", cst.Root.Find(call).Text())
+	}
+}
@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"fmt"
+)
+
+// ENTRY_POINT is the function the runtime starts a script with.
+const ENTRY_POINT = "main"
+
+// DeadCode is a function of the workspace nothing calls, or a global variable nothing reads.
+type DeadCode struct {
+	URI string
+	Symbol *Symbol
+}
+
+func (d DeadCode) Problem() Problem {
+	message := fmt.Sprintf("global variable '%s' is never read", d.Symbol.Name)
+	if d.Symbol.Kind == SYMBOL_FUNCTION {
+		message = fmt.Sprintf("function '%s' is never called", d.Symbol.Name)
+	}
+	return Problem{
+		Range: NodeRange(d.Symbol.Ident),
+		Severity: SEVERITY_HINT,
+		Message: message,
+		Tags: []Tag{TAG_UNNECESSARY},
+	}
+}
+
+// DeadCode finds the dead functions and global variables declared in a file of the workspace
+// folders. Uses from the files including it count, calls of a function from within itself do not.
+// Entry points are called by the runtime, like main and the functions handed over by name.
+func (w *Workspace) DeadCode(uri string, entryPoints map[string]bool) []DeadCode {
+	dead := []DeadCode{}
+	file, ok := w.Files[uri]
+	if !ok || !w.Contains(URIToPath(uri)) {
+		return dead
+	}
+	for _, decl := range file.Tree.File.Decls {
+		var ident *Ident
+		switch d := decl.(type) {
+		case *FuncDecl:
+			ident = d.Name
+		case *VarDecl:
+			ident = d.Name
+		default:
+			continue
+		}
+		symbol, ok := file.Resolution.Root.Symbols[ident.Name]
+		if !ok || symbol.Ident != ident || entryPoints[symbol.Name] {
+			continue
+		}
+		if !w.used(uri, symbol) {
+			dead = append(dead, DeadCode{URI: uri, Symbol: symbol})
+		}
+	}
+	return dead
+}
+
+// used reports whether a function is called, or a global variable read, anywhere but in itself.
+func (w *Workspace) used(uri string, symbol *Symbol) bool {
+	for _, reference := range w.References(Declaration{URI: uri, Symbol: symbol}) {
+		if symbol.Kind == SYMBOL_FUNCTION && reference.URI == uri && Contains(symbol.Decl, reference.Ident.Pos()) {
+			continue
+		}
+		if symbol.Kind == SYMBOL_GLOBAL && w.assigned(reference) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// assigned reports whether a reference is the target of a plain assignment, which does not read it.
+func (w *Workspace) assigned(reference Reference) bool {
+	assign, ok := w.Files[reference.URI].Tree.Parent(reference.Ident).(*AssignExpr)
+	return ok && assign.Op == "=" && assign.Target == Expr(reference.Ident)
+}
+
+// AllDeadCode is DeadCode for every file of the workspace folders.
+func (w *Workspace) AllDeadCode(entryPoints map[string]bool) []DeadCode {
+	dead := []DeadCode{}
+	for _, uri := range w.uris() {
+		dead = append(dead, w.DeadCode(uri, entryPoints)...)
+	}
+	return dead
+}
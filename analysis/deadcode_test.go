@@ -0,0 +1,65 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestDeadCode(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "long counter;\nlong function Helper() {\n\treturn counter;\n}\nvoid function Unused() {\n\tUnused();\n}\n",
+		// other.sct calls an Init of its own, the one of main.sct stays dead
+		"other.sct": "void function Init() {\n}\nvoid function Start() {\n\tInit();\n}\n",
+	})
+	state.ChangeConfiguration(logger, &lsp.Settings{Callbacks: []string{"OnClick"}})
+	other, main := scriptURI(state, "other.sct"), scriptURI(state, "main.sct")
+	text := "#include \"lib.sct\"\n" +
+		"long written;\n" +
+		"void function OnClick() {\n" +
+		"}\n" +
+		"void function main() {\n" +
+		"\twritten = Helper();\n" +
+		"\tcounter = 1;\n" +
+		"}\n" +
+		"void function Init() {\n" +
+		"}\n"
+	all := state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+	diagnostics := all[0].Diagnostics
+	if len(diagnostics) != 2 || diagnostics[0].Message != "global variable 'written' is never read" || diagnostics[1].Message != "function 'Init' is never called" {
+		t.Fatalf("Expected: written and Init reported, Actual: %v", diagnostics)
+	}
+	if diagnostics[0].Severity != int(analysis.SEVERITY_HINT) || len(diagnostics[0].Tags) != 1 || diagnostics[0].Tags[0] != int(analysis.TAG_UNNECESSARY) {
+		t.Fatalf("Expected: unnecessary hint, Actual: %v", diagnostics[0])
+	}
+
+	report := state.DeadCodeReport(1).Result
+	if len(report) != 4 {
+		t.Fatalf("Expected: 2 entries, Actual: %v", report)
+	}
+	if report[0].Name != "Unused" || report[0].Kind != "function" || report[0].Message != "function 'Unused' is never called" {
+		t.Fatalf("Expected: Unused first, Actual: %v", report[0])
+	}
+	if report[1].Name != "written" || report[1].Location.URI != main {
+		t.Fatalf("Expected: written in main.sct, Actual: %v", report[1])
+	}
+	if report[2].Name != "Init" || report[2].Location.URI != main || report[3].Name != "Start" || report[3].Location.URI != other {
+		t.Fatalf("Expected: Init of main.sct and Start of other.sct, Actual: %v", report[2:])
+	}
+}
+
+func TestDeadCodeWithoutInclude(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "long function Shared() {\n\treturn 1;\n}\n",
+	})
+	lib, main := scriptURI(state, "lib.sct"), scriptURI(state, "main.sct")
+	// main.sct calls Shared without including lib.sct
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: "void function main() {\n\tShared();\n}\n"})
+	all := state.OpenDocument(logger, lsp.TextDocumentItem{URI: lib, Version: 1, Text: "long function Shared() {\n\treturn 1;\n}\n"})
+	if len(all[0].Diagnostics) != 0 {
+		t.Fatalf("Expected: Shared is called, Actual: %v", all[0].Diagnostics)
+	}
+	if report := state.DeadCodeReport(1).Result; len(report) != 0 {
+		t.Fatalf("Expected: no dead code, Actual: %v", report)
+	}
+}
@@ -0,0 +1,66 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDefinition(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "long function Shared() {\n\treturn 1;\n}\n",
+		"other.sct": "\nlong function Shared() {\n\treturn 2;\n}\n",
+	},
+		analysis.NewBormFunction("Global", "Programm", "long", "GetActiveWindow", "", "Gibt das aktuelle Fenster zurück"),
+		analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text, string title", "Zeigt eine Meldung\nan"),
+	)
+	lib, other, main := scriptURI(state, "lib.sct"), scriptURI(state, "other.sct"), scriptURI(state, "main.sct")
+	text := "#include \"lib.sct\"\n" +
+		"void function main(string title) {\n" +
+		"\tlong n = Shared();\n" +
+		"\tMsgBox(\"\" + n, title);\n" +
+		"}\n"
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+
+	definition := func(line, character int) []lsp.Location {
+		return state.Definition(logger, 1, main, lsp.Position{Line: line, Character: character}).Result
+	}
+	expect := func(locations []lsp.Location, uri string, line, character int) {
+		t.Helper()
+		if len(locations) != 1 || locations[0].URI != uri || locations[0].Range.Start != (lsp.Position{Line: line, Character: character}) {
+			t.Fatalf("Expected: %s %d:%d, Actual: %v", uri, line, character, locations)
+		}
+	}
+	expect(definition(0, 3), lib, 0, 0)
+	expect(definition(3, 13), main, 2, 6)
+	expect(definition(3, 17), main, 1, 26)
+
+	// other.sct is not included, its Shared is not the one main.sct calls
+	expect(definition(2, 11), lib, 0, 14)
+	state.CloseDocument(logger, main)
+	text = strings.Replace(text, "#include \"lib.sct\"\n", "\n", 1)
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 2, Text: text})
+	shared := definition(2, 11)
+	if len(shared) != 2 || shared[0].URI != lib || shared[1].URI != other || shared[1].Range.Start.Line != 1 {
+		t.Fatalf("Expected: Shared in lib.sct and other.sct without the include, Actual: %v", shared)
+	}
+
+	builtin := definition(3, 2)
+	if len(builtin) != 1 || !strings.HasSuffix(builtin[0].URI, "/catalog.sct") {
+		t.Fatalf("Expected: MsgBox in catalog.sct, Actual: %v", builtin)
+	}
+	data, err := os.ReadFile(analysis.URIToPath(builtin[0].URI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.Split(string(data), "\n")[builtin[0].Range.Start.Line]
+	if line != "void function MsgBox(string text, string title);" || line[builtin[0].Range.Start.Character:builtin[0].Range.End.Character] != "MsgBox" {
+		t.Fatalf("Expected: declaration of MsgBox, Actual: %s %v", line, builtin[0].Range)
+	}
+
+	if locations := definition(1, 1); len(locations) != 0 {
+		t.Fatalf("Expected: nothing on a type, Actual: %v", locations)
+	}
+}
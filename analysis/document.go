@@ -0,0 +1,125 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"sort"
+)
+
+// Document is an open text document and everything derived from it.
+// Tree and Errors always belong to Text as of Version.
+type Document struct {
+	URI string
+	LanguageId string
+	Version int
+	Text string
+	Tree *Tree
+	Errors []SyntaxError
+	// Resolution binds the names of Tree, it is set by State after every change.
+	Resolution *Resolution
+	// Types holds the types of Tree and the type errors found in it, set along with Resolution.
+	Types *TypeInfo
+	// Includes are the include directives with the files they name, set along with Resolution.
+	Includes []Include
+	// Problems are all problems the analysis found in the document itself.
+	Problems []Problem
+	// Encoding is what the client counts characters in, see ToClient and FromClient.
+	Encoding PositionEncoding
+	// lines holds the byte offset every line starts at.
+	lines []int
+}
+
+func NewDocument(uri, languageId string, version int, text string) *Document {
+	d := &Document{URI: uri, LanguageId: languageId}
+	d.setText(version, text)
+	return d
+}
+
+func (d *Document) setText(version int, text string) {
+	file, errors := Parse(d.URI, text)
+	d.Version = version
+	d.Text = text
+	d.Tree = NewTree(file)
+	d.Errors = errors
+	d.lines = lineOffsets(text)
+}
+
+// Update applies the changes in order and brings the document to version.
+// Ranged changes only reparse the declarations they touch. Ranges are in the encoding of the client.
+func (d *Document) Update(version int, changes []lsp.TextDocumentChangeEvent) {
+	for _, change := range changes {
+		if change.Range == nil {
+			d.setText(version, change.Text)
+			continue
+		}
+		start := d.Offset(d.FromClient(change.Range.Start))
+		end := max(start, d.Offset(d.FromClient(change.Range.End)))
+		text := d.Text[:start] + change.Text + d.Text[end:]
+
+		file, errors := Reparse(d.Tree.File, text, start, end, start+len(change.Text))
+		d.Text = text
+		d.Tree = NewTree(file)
+		d.Errors = errors
+		d.lines = lineOffsets(text)
+	}
+	d.Version = version
+}
+
+// lineOffsets finds the start of every line. `\n`, `\r\n` and a lone `\r` end a line.
+func lineOffsets(text string) []int {
+	lines := []int{0}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				i++
+			}
+			lines = append(lines, i+1)
+		case '\n':
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+func (d *Document) LineCount() int {
+	return len(d.lines)
+}
+
+// Line returns the text of a line without its line break.
+func (d *Document) Line(line int) string {
+	if line < 0 || line >= len(d.lines) {
+		return ""
+	}
+	return d.Text[d.lines[line]:d.lineEnd(line)]
+}
+
+// lineEnd is the offset of the line break ending line, or the end of the text.
+func (d *Document) lineEnd(line int) int {
+	end := len(d.Text)
+	if line+1 < len(d.lines) {
+		end = d.lines[line+1]
+	}
+	for end > d.lines[line] && (d.Text[end-1] == '\n' || d.Text[end-1] == '\r') {
+		end--
+	}
+	return end
+}
+
+// Offset turns a position into a byte offset into the text. Positions past the end of a line
+// are clamped to the line break, positions past the last line to the end of the text.
+func (d *Document) Offset(pos lsp.Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lines) {
+		return len(d.Text)
+	}
+	return min(d.lines[pos.Line]+max(pos.Character, 0), d.lineEnd(pos.Line))
+}
+
+// Position turns a byte offset into the text into a position.
+func (d *Document) Position(offset int) lsp.Position {
+	offset = min(max(offset, 0), len(d.Text))
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+	return lsp.Position{Line: line, Character: offset - d.lines[line]}
+}
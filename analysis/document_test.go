@@ -0,0 +1,84 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestDocumentOffsets(t *testing.T) {
+	document := analysis.NewDocument("file:///test.sct", "bormscript", 1, "ab\r\ncd\re\nf")
+	if document.LineCount() != 4 {
+		t.Fatalf("Expected: 4 lines, Actual: %d", document.LineCount())
+	}
+	if document.Line(1) != "cd" {
+		t.Fatalf("Expected: %q, Actual: %q", "cd", document.Line(1))
+	}
+
+	positions := []struct {
+		pos lsp.Position
+		offset int
+	}{
+		{lsp.Position{Line: 0, Character: 1}, 1},
+		{lsp.Position{Line: 0, Character: 9}, 2},
+		{lsp.Position{Line: 1, Character: 2}, 6},
+		{lsp.Position{Line: 2, Character: 0}, 7},
+		{lsp.Position{Line: 3, Character: 1}, 10},
+		{lsp.Position{Line: 7, Character: 0}, 10},
+	}
+	for _, p := range positions {
+		if actual := document.Offset(p.pos); actual != p.offset {
+			t.Fatalf("Expected: %d, Actual: %d", p.offset, actual)
+		}
+	}
+	if actual := document.Position(7); actual != (lsp.Position{Line: 2, Character: 0}) {
+		t.Fatalf("Expected: 2:0, Actual: %v", actual)
+	}
+}
+
+func TestDocumentUpdate(t *testing.T) {
+	document := analysis.NewDocument("file:///test.sct", "bormscript", 1, "long a;\nvoid function main() {\n}\n")
+	document.Update(3, []lsp.TextDocumentChangeEvent{
+		{Range: &lsp.Range{Start: lsp.Position{Line: 0, Character: 5}, End: lsp.Position{Line: 0, Character: 6}}, Text: "count"},
+		{Range: &lsp.Range{Start: lsp.Position{Line: 1, Character: 23}, End: lsp.Position{Line: 1, Character: 23}}, Text: "\n\tcount++;"},
+	})
+
+	expected := "long count;\nvoid function main() {\n\tcount++;\n}\n"
+	if document.Text != expected {
+		t.Fatalf("Expected: %q, Actual: %q", expected, document.Text)
+	}
+	if document.Version != 3 || len(document.Errors) != 0 {
+		t.Fatalf("Expected: version 3 without errors, Actual: version %d, %v", document.Version, document.Errors)
+	}
+	if document.LineCount() != 5 {
+		t.Fatalf("Expected: 5 lines, Actual: %d", document.LineCount())
+	}
+}
+
+func TestDocumentEncodings(t *testing.T) {
+	// ä takes two bytes in UTF-8, 😀 four bytes and two UTF-16 code units
+	document := analysis.NewDocument("file:///test.sct", "bormscript", 1, "string s = \"ä😀\"; x")
+	byteColumn := lsp.Position{Line: 0, Character: 20}
+	expected := map[analysis.PositionEncoding]int{
+		analysis.ENCODING_UTF8: 20,
+		analysis.ENCODING_UTF16: 17,
+		analysis.ENCODING_UTF32: 16,
+	}
+	for encoding, character := range expected {
+		document.Encoding = encoding
+		client := document.ToClient(byteColumn)
+		if client.Character != character {
+			t.Fatalf("Expected: %d in %s, Actual: %d", character, encoding, client.Character)
+		}
+		if back := document.FromClient(client); back != byteColumn {
+			t.Fatalf("Expected: %v, Actual: %v", byteColumn, back)
+		}
+	}
+
+	if actual := analysis.NegotiateEncoding([]string{"utf-7", "utf-32", "utf-8"}); actual != analysis.ENCODING_UTF32 {
+		t.Fatalf("Expected: utf-32, Actual: %s", actual)
+	}
+	if actual := analysis.NegotiateEncoding(nil); actual != analysis.ENCODING_UTF16 {
+		t.Fatalf("Expected: utf-16, Actual: %s", actual)
+	}
+}
@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"unicode/utf8"
+)
+
+// PositionEncoding is what lsp.Position.Character counts on the client side.
+// Inside the analysis a character is always a byte offset into the line,
+// Document.ToClient and Document.FromClient convert at the protocol boundary.
+type PositionEncoding string
+
+const (
+	ENCODING_UTF8 PositionEncoding = "utf-8"
+	ENCODING_UTF16 PositionEncoding = "utf-16"
+	ENCODING_UTF32 PositionEncoding = "utf-32"
+)
+
+// NegotiateEncoding picks the first encoding the client offers that we support.
+// Clients that offer none only know UTF-16, the protocol default.
+func NegotiateEncoding(offered []string) PositionEncoding {
+	for _, encoding := range offered {
+		switch PositionEncoding(encoding) {
+		case ENCODING_UTF8, ENCODING_UTF16, ENCODING_UTF32:
+			return PositionEncoding(encoding)
+		}
+	}
+	return ENCODING_UTF16
+}
+
+// units is the number of code units r takes up in the encoding.
+func (e PositionEncoding) units(r rune, size int) int {
+	switch e {
+	case ENCODING_UTF8:
+		return size
+	case ENCODING_UTF32:
+		return 1
+	}
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// toUnits counts the code units of line[:column].
+func (e PositionEncoding) toUnits(line string, column int) int {
+	if e == ENCODING_UTF8 {
+		return column
+	}
+	units := 0
+	for offset := 0; offset < column && offset < len(line); {
+		r, size := utf8.DecodeRuneInString(line[offset:])
+		units += e.units(r, size)
+		offset += size
+	}
+	return units
+}
+
+// toColumn finds the byte column after the given number of code units. A position
+// in the middle of a character moves behind it, positions past the end stay at the end.
+func (e PositionEncoding) toColumn(line string, units int) int {
+	if e == ENCODING_UTF8 {
+		return min(units, len(line))
+	}
+	offset := 0
+	for units > 0 && offset < len(line) {
+		r, size := utf8.DecodeRuneInString(line[offset:])
+		units -= e.units(r, size)
+		offset += size
+	}
+	return offset
+}
+
+func (d *Document) encoding() PositionEncoding {
+	if d.Encoding == "" {
+		return ENCODING_UTF16
+	}
+	return d.Encoding
+}
+
+// ToClient converts a position of the analysis into the encoding of the client.
+func (d *Document) ToClient(pos lsp.Position) lsp.Position {
+	return lsp.Position{Line: pos.Line, Character: d.encoding().toUnits(d.Line(pos.Line), pos.Character)}
+}
+
+// FromClient converts a position sent by the client into a byte position.
+func (d *Document) FromClient(pos lsp.Position) lsp.Position {
+	return lsp.Position{Line: pos.Line, Character: d.encoding().toColumn(d.Line(pos.Line), pos.Character)}
+}
+
+func (d *Document) RangeToClient(r lsp.Range) lsp.Range {
+	return lsp.Range{Start: d.ToClient(r.Start), End: d.ToClient(r.End)}
+}
+
+func (d *Document) RangeFromClient(r lsp.Range) lsp.Range {
+	return lsp.Range{Start: d.FromClient(r.Start), End: d.FromClient(r.End)}
+}
@@ -0,0 +1,316 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"fmt"
+	"sort"
+)
+
+// scalarTypes are the types whose variables hold no value until one is assigned.
+// Lists, maps, arrays and class instances are created along with the variable.
+var scalarTypes = map[string]bool{
+	"bool": true,
+	"short": true,
+	"int": true,
+	"long": true,
+	"double": true,
+	"string": true,
+	"variant": true,
+}
+
+// CheckFlow analyses the control flow of every function of a resolved file. It reports
+// functions with a result that can reach their end without a return, statements that can
+// never run and local variables that are read before a value was assigned to them on some path.
+func CheckFlow(file *File, resolution *Resolution) []Problem {
+	problems := []Problem{}
+	for _, decl := range file.Decls {
+		function, ok := decl.(*FuncDecl)
+		if !ok || function.Body == nil {
+			continue
+		}
+		f := &flow{cfg: NewCFG(function), resolution: resolution}
+		f.reachable = f.cfg.Reachable()
+		f.missingReturn(function)
+		f.unreachable(function.Body.List)
+		f.unassigned()
+		problems = append(problems, f.problems...)
+	}
+	return problems
+}
+
+type flow struct {
+	cfg *CFG
+	resolution *Resolution
+	reachable map[*BasicBlock]bool
+	problems []Problem
+}
+
+func (f *flow) report(start, end lsp.Position, severity Severity, format string, args ...any) *Problem {
+	f.problems = append(f.problems, Problem{
+		Range: lsp.Range{Start: start, End: end},
+		Severity: severity,
+		Message: fmt.Sprintf(format, args...),
+	})
+	return &f.problems[len(f.problems)-1]
+}
+
+func (f *flow) missingReturn(function *FuncDecl) {
+	result := TypeName(function.Type)
+	if result == TYPE_VOID || result == TYPE_UNKNOWN || !f.reachable[f.cfg.End] {
+		return
+	}
+	// the closing brace
+	end := function.Body.End()
+	start := end
+	if start.Character > 0 {
+		start.Character--
+	}
+	f.report(start, end, SEVERITY_ERROR, "missing return at the end of function '%s', it returns %s", function.Name.Name, result)
+}
+
+// unreachable reports the first statement of a list that cannot run, up to the end of the list.
+func (f *flow) unreachable(list []Stmt) {
+	for i, stmt := range list {
+		if _, ok := stmt.(*EmptyStmt); ok {
+			continue
+		}
+		if block, ok := f.cfg.Starts[stmt]; ok && !f.reachable[block] {
+			problem := f.report(stmt.Pos(), list[len(list)-1].End(), SEVERITY_WARNING, "unreachable code")
+			problem.Tags = []Tag{TAG_UNNECESSARY}
+			return
+		}
+		for _, body := range nestedStmts(list[i]) {
+			f.unreachable(body)
+		}
+	}
+}
+
+// nestedStmts returns the statement lists within a statement.
+func nestedStmts(stmt Stmt) [][]Stmt {
+	lists := [][]Stmt{}
+	add := func(stmt Stmt) {
+		if block, ok := stmt.(*BlockStmt); ok {
+			lists = append(lists, block.List)
+		} else if stmt != nil {
+			lists = append(lists, []Stmt{stmt})
+		}
+	}
+	switch s := stmt.(type) {
+	case *BlockStmt:
+		lists = append(lists, s.List)
+	case *IfStmt:
+		add(s.Body)
+		add(s.Else)
+	case *WhileStmt:
+		add(s.Body)
+	case *ForStmt:
+		add(s.Body)
+	}
+	return lists
+}
+
+// assignedSet holds the local variables that are assigned on every path.
+type assignedSet map[*Symbol]bool
+
+func (s assignedSet) copy() assignedSet {
+	c := assignedSet{}
+	for symbol := range s {
+		c[symbol] = true
+	}
+	return c
+}
+
+// unassigned solves which locals are assigned on every path into each block,
+// then reports the first read of every variable that is not.
+func (f *flow) unassigned() {
+	in := map[*BasicBlock]assignedSet{}
+	out := map[*BasicBlock]assignedSet{}
+	for changed := true; changed; {
+		changed = false
+		for _, block := range f.cfg.Blocks {
+			if !f.reachable[block] {
+				continue
+			}
+			set, ok := f.meet(block, out)
+			if !ok {
+				continue
+			}
+			in[block] = set.copy()
+			(&assignment{flow: f, set: set}).block(block)
+			if old, ok := out[block]; !ok || len(old) != len(set) {
+				out[block] = set
+				changed = true
+			}
+		}
+	}
+
+	reads := map[*Symbol]*Ident{}
+	for _, block := range f.cfg.Blocks {
+		if set, ok := in[block]; ok {
+			(&assignment{flow: f, set: set, reads: reads}).block(block)
+		}
+	}
+	idents := []*Ident{}
+	for _, ident := range reads {
+		idents = append(idents, ident)
+	}
+	sort.Slice(idents, func(i, j int) bool { return PositionLess(idents[i].Pos(), idents[j].Pos()) })
+	for _, ident := range idents {
+		f.report(ident.Pos(), ident.End(), SEVERITY_WARNING, "'%s' may be used before it is assigned", ident.Name)
+	}
+}
+
+// meet intersects the sets of the predecessors solved so far, the entry starts empty.
+func (f *flow) meet(block *BasicBlock, out map[*BasicBlock]assignedSet) (assignedSet, bool) {
+	if block == f.cfg.Entry {
+		return assignedSet{}, true
+	}
+	var set assignedSet
+	for _, pred := range block.Preds {
+		predSet, ok := out[pred]
+		if !ok {
+			continue
+		}
+		if set == nil {
+			set = predSet.copy()
+			continue
+		}
+		for symbol := range set {
+			if !predSet[symbol] {
+				delete(set, symbol)
+			}
+		}
+	}
+	return set, set != nil
+}
+
+// assignment follows the assignments through the nodes of a block. With reads set,
+// it records the first read of every tracked variable that is not assigned.
+type assignment struct {
+	flow *flow
+	set assignedSet
+	reads map[*Symbol]*Ident
+}
+
+func (a *assignment) tracked(ident *Ident) *Symbol {
+	symbol, ok := a.flow.resolution.Symbols[ident]
+	if !ok || symbol.Kind != SYMBOL_LOCAL || !scalarTypes[TypeName(symbol.Type)] {
+		return nil
+	}
+	return symbol
+}
+
+func (a *assignment) block(block *BasicBlock) {
+	for _, node := range block.Nodes {
+		switch n := node.(type) {
+		case *VarDecl:
+			a.expr(n.Value)
+			if symbol := a.tracked(n.Name); symbol != nil && n.Value != nil {
+				a.set[symbol] = true
+			} else if symbol != nil {
+				// declared again on every pass through a loop
+				delete(a.set, symbol)
+			}
+		case *ExprStmt:
+			a.expr(n.X)
+		case *ReturnStmt:
+			a.expr(n.Result)
+		case Expr:
+			a.expr(n)
+		}
+	}
+}
+
+func (a *assignment) assign(target Expr) {
+	if ident, ok := target.(*Ident); ok {
+		if symbol := a.tracked(ident); symbol != nil {
+			a.set[symbol] = true
+		}
+		return
+	}
+	// an element or member of the target is assigned, the target itself is read
+	switch t := target.(type) {
+	case *IndexExpr:
+		a.expr(t.X)
+		a.expr(t.Index)
+	case *MemberExpr:
+		a.expr(t.X)
+	default:
+		a.expr(target)
+	}
+}
+
+func (a *assignment) expr(expr Expr) {
+	switch e := expr.(type) {
+	case nil:
+	case *Ident:
+		symbol := a.tracked(e)
+		if symbol == nil || a.set[symbol] || a.reads == nil {
+			return
+		}
+		if first, ok := a.reads[symbol]; !ok || PositionLess(e.Pos(), first.Pos()) {
+			a.reads[symbol] = e
+		}
+	case *AssignExpr:
+		if e.Op == "=" {
+			a.expr(e.Value)
+			a.assign(e.Target)
+		} else {
+			a.expr(e.Target)
+			a.expr(e.Value)
+		}
+	case *BinaryExpr:
+		a.expr(e.X)
+		if e.Op == "&&" || e.Op == "||" {
+			// the right operand may not be evaluated, its assignments do not count
+			(&assignment{flow: a.flow, set: a.set.copy(), reads: a.reads}).expr(e.Y)
+		} else {
+			a.expr(e.Y)
+		}
+	case *CallExpr:
+		a.call(e)
+	case *UnaryExpr:
+		a.expr(e.X)
+	case *ParenExpr:
+		a.expr(e.X)
+	case *IndexExpr:
+		a.expr(e.X)
+		a.expr(e.Index)
+	case *MemberExpr:
+		a.expr(e.X)
+	}
+}
+
+// call treats variables passed by reference as assigned. Where the parameters are not
+// known, like for methods or functions of other files, every variable passed might be.
+func (a *assignment) call(call *CallExpr) {
+	var params []BormParam
+	known := false
+	if ident, ok := call.Fun.(*Ident); ok {
+		if symbol, ok := a.flow.resolution.Symbols[ident]; ok {
+			switch symbol.Kind {
+			case SYMBOL_FUNCTION:
+				params, known = FuncSignature(symbol.Decl.(*FuncDecl)).Params, true
+			case SYMBOL_BUILTIN:
+				params, known = symbol.Builtin.Params, true
+			case SYMBOL_TYPE:
+				known = true
+			}
+		}
+	} else {
+		a.expr(call.Fun)
+	}
+
+	byRef := []Expr{}
+	for i, arg := range call.Args {
+		_, isIdent := arg.(*Ident)
+		if isIdent && (!known || (i < len(params) && params[i].ByRef)) {
+			byRef = append(byRef, arg)
+			continue
+		}
+		a.expr(arg)
+	}
+	for _, arg := range byRef {
+		a.assign(arg)
+	}
+}
@@ -0,0 +1,102 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"testing"
+)
+
+func checkFlow(t *testing.T, text string) []analysis.Problem {
+	file, errors := analysis.Parse("file:///test.sct", text)
+	if len(errors) != 0 {
+		t.Fatalf("Expected: no errors, Actual: %v", errors)
+	}
+	catalog := analysis.NewCatalog([]analysis.BormFunction{
+		analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text, string title", ""),
+	})
+	return analysis.CheckFlow(file, analysis.Resolve(file, catalog))
+}
+
+func TestFlowValidScript(t *testing.T) {
+	problems := checkFlow(t, `
+void function Out(long &value) {
+	value = 1;
+}
+long function Forever() {
+	while (true) {
+		return 1;
+	}
+}
+long function Branches(long a) {
+	long x;
+	string s;
+	if (a > 0) {
+		x = 1;
+	} else {
+		Out(x);
+	}
+	Unknown(s);
+	for (long i = 0; i < a; i++) {
+		if (i == x) {
+			continue;
+		}
+		return i + x + string(s);
+	}
+	return 0;
+}
+`)
+	if len(problems) != 0 {
+		t.Fatalf("Expected: no problems, Actual: %v", problems)
+	}
+}
+
+func TestFlowProblems(t *testing.T) {
+	problems := checkFlow(t, `
+bool function CreateDBObject(long id) {
+	if (id > 0) {
+		return true;
+	}
+}
+long function Both(long a) {
+	if (a > 0) {
+		return 1;
+	} else {
+		return 2;
+	}
+	MsgBox("never", "");
+	a = 3;
+}
+void function Loop(bool c) {
+	long x;
+	if (c) {
+		x = 1;
+	}
+	while (c) {
+		long y;
+		y += x;
+		break;
+		MsgBox("after break", "");
+	}
+}
+`)
+	expected := []struct {
+		line int
+		message string
+	}{
+		{5, "missing return at the end of function 'CreateDBObject', it returns bool"},
+		{12, "unreachable code"},
+		{24, "unreachable code"},
+		{22, "'y' may be used before it is assigned"},
+		{22, "'x' may be used before it is assigned"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected: %d problems, Actual: %v", len(expected), problems)
+	}
+	for i, problem := range problems {
+		if problem.Message != expected[i].message || problem.Range.Start.Line != expected[i].line {
+			t.Fatalf("Expected: %s on line %d, Actual: %s on line %d", expected[i].message, expected[i].line, problem.Message, problem.Range.Start.Line)
+		}
+	}
+	if problems[1].Range.End.Line != 13 {
+		t.Fatalf("Expected: unreachable code up to line 13, Actual: %v", problems[1].Range)
+	}
+}
@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeConfig says where included files are searched.
+type IncludeConfig struct {
+	// BaseDirectory replaces the `<BD>` macro, the installation directory of BORM.
+	BaseDirectory string
+	// IncludePaths are searched in order after the directory of the including file.
+	IncludePaths []string
+}
+
+// Include is an include directive together with the file it names.
+type Include struct {
+	Directive *IncludeDirective
+	// Path is the file found on disk, empty if there is none.
+	Path string
+	URI string
+}
+
+const baseDirectoryMacro = "<BD>"
+
+// ResolveIncludes finds the files the include directives of a file name. `#include "x"` is
+// searched next to the including file first, `#include <x>` only in the include paths.
+// Paths may use backslashes, differ from the file on disk in case and leave out `.sct`.
+// Includes that cannot be found are reported as problems.
+func ResolveIncludes(file *File, config IncludeConfig) ([]Include, []Problem) {
+	includes := []Include{}
+	problems := []Problem{}
+	dir := ""
+	if path := URIToPath(file.URI); path != "" {
+		dir = filepath.Dir(path)
+	}
+
+	for _, decl := range file.Decls {
+		directive, ok := decl.(*IncludeDirective)
+		if !ok || directive.Path == "" {
+			continue
+		}
+		include := Include{Directive: directive}
+		path, err := findInclude(directive, dir, config)
+		if err != nil {
+			problems = append(problems, Problem{
+				Range: NodeRange(directive),
+				Severity: SEVERITY_ERROR,
+				Message: err.Error(),
+			})
+		} else {
+			include.Path = path
+			include.URI = PathToURI(path)
+		}
+		includes = append(includes, include)
+	}
+	return includes, problems
+}
+
+func findInclude(directive *IncludeDirective, dir string, config IncludeConfig) (string, error) {
+	name := strings.ReplaceAll(directive.Path, "\\", "/")
+	if len(name) >= len(baseDirectoryMacro) && strings.EqualFold(name[:len(baseDirectoryMacro)], baseDirectoryMacro) {
+		if config.BaseDirectory == "" {
+			return "", fmt.Errorf("cannot resolve '%s', the base directory for %s is not configured", directive.Path, baseDirectoryMacro)
+		}
+		name = filepath.ToSlash(config.BaseDirectory) + "/" + strings.TrimLeft(name[len(baseDirectoryMacro):], "/")
+	}
+	name = filepath.FromSlash(name)
+
+	candidates := []string{}
+	if isAbsolute(name) {
+		candidates = append(candidates, name)
+	} else {
+		if !directive.Angled && dir != "" {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+		for _, includePath := range config.IncludePaths {
+			candidates = append(candidates, filepath.Join(includePath, name))
+		}
+	}
+	for _, candidate := range candidates {
+		if path, ok := findFile(candidate); ok {
+			return path, nil
+		}
+		if !IsScript(candidate) {
+			if path, ok := findFile(candidate + ".sct"); ok {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("cannot find included file '%s'", directive.Path)
+}
+
+// isAbsolute also takes Windows paths with a drive letter as absolute on other systems,
+// scripts are written on Windows.
+func isAbsolute(path string) bool {
+	return filepath.IsAbs(path) || (len(path) >= 3 && path[1] == ':' && (path[2] == '/' || path[2] == '\\'))
+}
+
+// findFile looks for a regular file, ignoring the case of every part of the path.
+func findFile(path string) (string, bool) {
+	return findEntry(path, false)
+}
+
+func findEntry(path string, isDir bool) (string, bool) {
+	if info, err := os.Stat(path); err == nil {
+		return path, info.IsDir() == isDir && (isDir || info.Mode().IsRegular())
+	}
+	path = filepath.Clean(path)
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	if name == "" || dir == path {
+		return "", false
+	}
+	dir, ok := findEntry(dir, true)
+	if !ok {
+		return "", false
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name(), name) && entry.IsDir() == isDir && (isDir || entry.Type().IsRegular()) {
+			return filepath.Join(dir, entry.Name()), true
+		}
+	}
+	return "", false
+}
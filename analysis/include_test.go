@@ -0,0 +1,58 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveIncludes(t *testing.T) {
+	base := t.TempDir()
+	root := t.TempDir()
+	borm := filepath.Join(base, "bin", "borm.sct")
+	writeScript(t, borm, "")
+	helper := filepath.Join(root, "scripts", "Helper.sct")
+	writeScript(t, helper, "")
+	shared := filepath.Join(root, "shared", "common.sct")
+	writeScript(t, shared, "")
+
+	text := "#include \"<BD>\\BIN\\Borm.sct\"\n" +
+		"#include \"helper\"\n" +
+		"#include <Common.SCT>\n" +
+		"#include <helper.sct>\n" +
+		"#include \"missing.sct\"\n"
+	file, _ := analysis.Parse(analysis.PathToURI(filepath.Join(root, "scripts", "main.sct")), text)
+	config := analysis.IncludeConfig{BaseDirectory: base, IncludePaths: []string{filepath.Join(root, "shared")}}
+	includes, problems := analysis.ResolveIncludes(file, config)
+
+	expected := []string{borm, helper, shared, "", ""}
+	if len(includes) != len(expected) {
+		t.Fatalf("Expected: %d includes, Actual: %d", len(expected), len(includes))
+	}
+	for i, path := range expected {
+		if includes[i].Path != path {
+			t.Fatalf("Expected: %q, Actual: %q", path, includes[i].Path)
+		}
+	}
+	if includes[0].URI != analysis.PathToURI(borm) {
+		t.Fatalf("Expected: %s, Actual: %s", analysis.PathToURI(borm), includes[0].URI)
+	}
+
+	messages := []string{"cannot find included file 'helper.sct'", "cannot find included file 'missing.sct'"}
+	if len(problems) != len(messages) {
+		t.Fatalf("Expected: %d problems, Actual: %v", len(messages), problems)
+	}
+	for i, message := range messages {
+		if problems[i].Message != message {
+			t.Fatalf("Expected: %s, Actual: %s", message, problems[i].Message)
+		}
+		if problems[i].Range.Start.Line != 3+i {
+			t.Fatalf("Expected: line %d, Actual: %d", 3+i, problems[i].Range.Start.Line)
+		}
+	}
+
+	_, problems = analysis.ResolveIncludes(file, analysis.IncludeConfig{})
+	if problems[0].Message != "cannot resolve '<BD>\\BIN\\Borm.sct', the base directory for <BD> is not configured" {
+		t.Fatalf("Expected: base directory not configured, Actual: %s", problems[0].Message)
+	}
+}
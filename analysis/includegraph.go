@@ -0,0 +1,120 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// includeStep is an include directive on the way from a file to one it includes transitively.
+type includeStep struct {
+	// URI is the file the directive is written in.
+	URI string
+	Include Include
+}
+
+// IncludedFiles returns every file a file includes, directly or transitively, in the order
+// they are first reached. Files that are not indexed are not followed.
+func (w *Workspace) IncludedFiles(uri string) []string {
+	files := []string{}
+	seen := map[string]bool{uri: true}
+	var walk func(file string)
+	walk = func(file string) {
+		indexed, ok := w.Files[file]
+		if !ok {
+			return
+		}
+		for _, include := range indexed.Includes {
+			if include.URI != "" && !seen[include.URI] {
+				seen[include.URI] = true
+				files = append(files, include.URI)
+				walk(include.URI)
+			}
+		}
+	}
+	walk(uri)
+	return files
+}
+
+// IncludeProblems reports the include cycles a file is part of and the files it includes
+// more than once, directly or through other files. Problems are reported on the include
+// directive of the file itself, the chains of includes go into the related information.
+// Cycles and duplicates entirely within an included file are left to that file.
+func (w *Workspace) IncludeProblems(uri string) []Problem {
+	problems := []Problem{}
+	first := map[string][]includeStep{}
+	onChain := map[string]bool{}
+	var walk func(file string, chain []includeStep)
+	walk = func(file string, chain []includeStep) {
+		indexed, ok := w.Files[file]
+		if !ok {
+			return
+		}
+		onChain[file] = true
+		defer delete(onChain, file)
+		for _, include := range indexed.Includes {
+			if include.URI == "" {
+				continue
+			}
+			next := append(chain[:len(chain):len(chain)], includeStep{URI: file, Include: include})
+			switch earlier, seen := first[include.URI]; {
+			case include.URI == uri:
+				problems = append(problems, cycleProblem(next))
+			case onChain[include.URI]:
+				// a cycle further down, without this file
+			case seen:
+				if earlier[0].Include.Directive != next[0].Include.Directive {
+					problems = append(problems, duplicateProblem(next, earlier))
+				}
+			default:
+				first[include.URI] = next
+				walk(include.URI, next)
+			}
+		}
+	}
+	walk(uri, []includeStep{})
+	return problems
+}
+
+func includeName(include Include) string {
+	return filepath.Base(include.Path)
+}
+
+func chainInformation(chain []includeStep) []RelatedInformation {
+	related := []RelatedInformation{}
+	for _, step := range chain {
+		related = append(related, RelatedInformation{
+			URI: step.URI,
+			Range: NodeRange(step.Include.Directive),
+			Message: fmt.Sprintf("includes '%s'", includeName(step.Include)),
+		})
+	}
+	return related
+}
+
+func cycleProblem(chain []includeStep) Problem {
+	names := []string{filepath.Base(URIToPath(chain[0].URI))}
+	for _, step := range chain {
+		names = append(names, includeName(step.Include))
+	}
+	return Problem{
+		Range: NodeRange(chain[0].Include.Directive),
+		Severity: SEVERITY_ERROR,
+		Message: fmt.Sprintf("include cycle: %s", strings.Join(names, " -> ")),
+		Related: chainInformation(chain),
+	}
+}
+
+func duplicateProblem(chain, earlier []includeStep) Problem {
+	target := chain[len(chain)-1].Include
+	message := fmt.Sprintf("'%s' is already included", includeName(target))
+	if len(earlier) > 1 {
+		message += fmt.Sprintf(" through '%s'", includeName(earlier[0].Include))
+	}
+	return Problem{
+		Range: NodeRange(chain[0].Include.Directive),
+		Severity: SEVERITY_WARNING,
+		Message: message,
+		Related: chainInformation(earlier),
+	}
+}
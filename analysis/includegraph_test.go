@@ -0,0 +1,55 @@
+package analysis_test
+
+import (
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestIncludeGraph(t *testing.T) {
+	text := "#include \"b.sct\"\n#include \"c.sct\"\n#include \"d.sct\"\nvoid function main() {\n\tD();\n}\n"
+	state := newWorkspaceState(t, map[string]string{
+		"b.sct": "#include \"d.sct\"\n",
+		"c.sct": "#include \"d.sct\"\n",
+		"d.sct": "long function D() {\n\treturn 1;\n}\n",
+		"cycle.sct": "#include \"main.sct\"\n",
+		"main.sct": text,
+	})
+	d, cycle, main := scriptURI(state, "d.sct"), scriptURI(state, "cycle.sct"), scriptURI(state, "main.sct")
+
+	all := state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+	diagnostics := all[0].Diagnostics
+	messages := []string{"'d.sct' is already included through 'b.sct'", "'d.sct' is already included through 'b.sct'"}
+	if len(diagnostics) != len(messages) {
+		t.Fatalf("Expected: %d diagnostics, Actual: %v", len(messages), diagnostics)
+	}
+	for i, message := range messages {
+		if diagnostics[i].Message != message || diagnostics[i].Range.Start.Line != i+1 {
+			t.Fatalf("Expected: %s on line %d, Actual: %v", message, i+1, diagnostics[i])
+		}
+	}
+	related := diagnostics[0].RelatedInformation
+	if len(related) != 2 || related[0].Location.URI != main || related[1].Location.URI != scriptURI(state, "b.sct") {
+		t.Fatalf("Expected: chain main.sct -> b.sct -> d.sct, Actual: %v", related)
+	}
+
+	files := state.Workspace.IncludedFiles(main)
+	if len(files) != 3 || files[1] != d {
+		t.Fatalf("Expected: b.sct, d.sct and c.sct included, Actual: %v", files)
+	}
+
+	// editing an included file diagnoses the files including it again
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: d, Version: 1, Text: "long function D() {\n\treturn 1;\n}\n"})
+	all = state.UpdateDocument(logger, lsp.VersionedTextDocumentIdentifier{TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: d}, Version: 2}, []lsp.TextDocumentChangeEvent{{Text: "#include \"cycle.sct\"\nlong function D() {\n\treturn 1;\n}\n"}})
+	var mainDiagnostics []lsp.Diagnostic
+	for _, params := range all {
+		if params.URI == main {
+			mainDiagnostics = params.Diagnostics
+		}
+	}
+	if len(mainDiagnostics) != 3 || mainDiagnostics[0].Message != "include cycle: main.sct -> b.sct -> d.sct -> cycle.sct -> main.sct" {
+		t.Fatalf("Expected: include cycle through d.sct, Actual: %v", mainDiagnostics)
+	}
+	if len(mainDiagnostics[0].RelatedInformation) != 4 || mainDiagnostics[0].RelatedInformation[3].Location.URI != cycle {
+		t.Fatalf("Expected: cycle.sct last in the chain, Actual: %v", mainDiagnostics[0].RelatedInformation)
+	}
+}
@@ -0,0 +1,145 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"reflect"
+	"sort"
+)
+
+// declInfo remembers how a top level declaration was parsed. A declaration only depends on
+// the tokens up to its horizon and on the error left behind by the one before it, so as long
+// as an edit stays clear of both it parses to the same nodes and can be reused.
+type declInfo struct {
+	// start and end are token indexes, end is where the next declaration starts.
+	start int
+	end int
+	// offset is the byte offset of the first token.
+	offset int
+	// horizon is the end offset of the furthest token the parser looked at.
+	horizon int
+	errors []SyntaxError
+	// lastError is the offset of the last error if it lies at or behind end, otherwise -1.
+	// Errors at the same token are only reported once, so it is carried into the next declaration.
+	lastError int
+}
+
+// Reparse updates a file parsed from the old text after the bytes [start, oldEnd) were replaced,
+// giving newText with the replacement ending at newEnd. Declarations in front of the edit are kept,
+// parsing starts at the first declaration the edit could affect and stops as soon as it is back
+// in step with a declaration behind the edit, which is moved to its new place. The result is the
+// same as parsing newText from scratch. The nodes of old are reused, old must not be used afterwards.
+func Reparse(old *File, newText string, start, oldEnd, newEnd int) (*File, []SyntaxError) {
+	if len(old.decls) != len(old.Decls) || start > oldEnd || start > newEnd {
+		return Parse(old.URI, newText)
+	}
+	delta := newEnd - oldEnd
+
+	keep := 0
+	for keep < len(old.decls) && old.decls[keep].horizon < start {
+		keep++
+	}
+
+	// declarations behind the edit the parser may fall back in step with
+	tail := map[int]int{}
+	for i := keep; i < len(old.decls); i++ {
+		if old.decls[i].offset >= oldEnd {
+			tail[old.decls[i].offset+delta] = i
+		}
+	}
+
+	p := NewParser(newText)
+	file := p.newFile(old.URI)
+	file.Decls = append(file.Decls, old.Decls[:keep]...)
+	file.decls = append(file.decls, old.decls[:keep]...)
+	if keep > 0 {
+		p.pos = old.decls[keep-1].end
+		p.lastErrorOffset = old.decls[keep-1].lastError
+	}
+
+	for !p.atEOF() {
+		if i, ok := tail[p.peek().Offset]; ok && p.inStep(old, i, delta) {
+			p.appendTail(file, old, i, delta)
+			break
+		}
+		p.parseTopLevel(file)
+	}
+
+	errors := p.lexErrors
+	for _, info := range file.decls {
+		errors = append(errors, info.errors...)
+	}
+	sort.SliceStable(errors, func(i, j int) bool {
+		return PositionLess(errors[i].Range.Start, errors[j].Range.Start)
+	})
+	return file, errors
+}
+
+// inStep reports whether parsing on from the current token gives the old declaration i and all
+// after it: the token is where i started and the error carried over into it is the same.
+func (p *Parser) inStep(old *File, i, delta int) bool {
+	carried := -1
+	if p.lastErrorOffset >= p.peek().Offset {
+		carried = p.lastErrorOffset
+	}
+	previous := -1
+	if i > 0 {
+		previous = old.decls[i-1].lastError
+	}
+	if previous < 0 {
+		return carried < 0
+	}
+	return carried == previous+delta
+}
+
+// appendTail moves the old declarations from i on behind the declarations parsed so far.
+func (p *Parser) appendTail(file *File, old *File, i, delta int) {
+	token := p.peek()
+	from := old.Decls[i].Pos()
+	to := token.Start
+	shift := func(pos lsp.Position) lsp.Position {
+		if pos.Line == from.Line {
+			return lsp.Position{Line: to.Line, Character: pos.Character - from.Character + to.Character}
+		}
+		return lsp.Position{Line: pos.Line - from.Line + to.Line, Character: pos.Character}
+	}
+	shiftRange := func(r lsp.Range) lsp.Range {
+		return lsp.Range{Start: shift(r.Start), End: shift(r.End)}
+	}
+
+	moved := p.pos - old.decls[i].start
+	for j := i; j < len(old.Decls); j++ {
+		shiftPositions(old.Decls[j], shift)
+		info := old.decls[j]
+		info.start += moved
+		info.end += moved
+		info.offset += delta
+		info.horizon += delta
+		if info.lastError >= 0 {
+			info.lastError += delta
+		}
+		info.errors = append([]SyntaxError{}, info.errors...)
+		for k := range info.errors {
+			info.errors[k].Range = shiftRange(info.errors[k].Range)
+		}
+		file.Decls = append(file.Decls, old.Decls[j])
+		file.decls = append(file.decls, info)
+	}
+}
+
+var positionType = reflect.TypeOf(lsp.Position{})
+
+// shiftPositions moves every position in the subtree of node.
+func shiftPositions(node Node, shift func(lsp.Position) lsp.Position) {
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		value := reflect.ValueOf(n).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			if field := value.Field(i); field.Type() == positionType {
+				field.Set(reflect.ValueOf(shift(field.Interface().(lsp.Position))))
+			}
+		}
+		return true
+	})
+}
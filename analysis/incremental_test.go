@@ -0,0 +1,80 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+const incrementalScript = `#include "<BD>\BIN\Borm.sct"
+
+long count = 0;
+
+// adds up a list
+long function Sum(list<long> values) {
+	long sum = 0;
+	for (long i = 0; i < values.Count(); ++i) {
+		sum += values[i];
+	}
+	return sum;
+}
+
+/* entry point */
+void function main() {
+	string s = "text";
+	if (Sum(values) > 10) {
+		MsgBox(s, "Title");
+	} else {
+		count++;
+	}
+}
+`
+
+func checkReparse(t *testing.T, text string, start, end int, insert string) string {
+	file, _ := analysis.Parse("file:///test.sct", text)
+	newText := text[:start] + insert + text[end:]
+	actual, actualErrors := analysis.Reparse(file, newText, start, end, start+len(insert))
+	expected, expectedErrors := analysis.Parse("file:///test.sct", newText)
+	if !reflect.DeepEqual(expected, actual) || !reflect.DeepEqual(expectedErrors, actualErrors) {
+		t.Fatalf("Expected: reparse equal to full parse, Actual: differs after replacing %q at %d-%d with %q\n%s",
+			text[start:end], start, end, insert, newText)
+	}
+	return newText
+}
+
+func TestReparseSingleEdits(t *testing.T) {
+	edits := []struct {
+		start, end int
+		insert string
+	}{
+		{0, 0, "x"},
+		{len(incrementalScript), len(incrementalScript), "long tail;"},
+		{30, 30, "\n\n"},
+		{45, 46, ""},
+		{120, 120, "}"},
+		{120, 120, "{"},
+		{150, 150, "/*"},
+		{150, 150, "\""},
+		{100, 200, ""},
+		{30, 31, "long function Broken(\n"},
+	}
+	for _, edit := range edits {
+		checkReparse(t, incrementalScript, edit.start, edit.end, edit.insert)
+	}
+}
+
+func TestReparseRandomEdits(t *testing.T) {
+	snippets := []string{"", "x", " ", "\n", "\r\n", ";", "{", "}", "(", ")", "/*", "*/", "//", "\"", "#include",
+		"long function f() {", "void function g(string s) {}\n", "long y = 1;", "else", "return", "<", ">"}
+	random := rand.New(rand.NewSource(7))
+	text := incrementalScript
+	for i := 0; i < 2000; i++ {
+		start := random.Intn(len(text) + 1)
+		end := min(len(text), start+random.Intn(4))
+		text = checkReparse(t, text, start, end, snippets[random.Intn(len(snippets))])
+		if len(text) > 2*len(incrementalScript) || i%200 == 0 {
+			text = incrementalScript
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"fmt"
+)
+
+type SyntaxError struct {
+	Range lsp.Range
+	Message string
+}
+
+// operators lists every operator longest first, so the lexer can match greedily.
+var operators = []string{
+	"==", "!=", "<=", ">=", "&&", "||", "++", "--", "+=", "-=", "*=", "/=",
+	"+", "-", "*", "/", "%", "=", "<", ">", "!", "&", "|",
+}
+
+const punctuation = "(){}[],;.:"
+
+type Lexer struct {
+	src string
+	offset int
+	line int
+	lineStart int
+	Errors []SyntaxError
+}
+
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: src}
+}
+
+func (l *Lexer) position() lsp.Position {
+	return lsp.Position{Line: l.line, Character: l.offset - l.lineStart}
+}
+
+func (l *Lexer) peek(ahead int) byte {
+	if l.offset+ahead >= len(l.src) {
+		return 0
+	}
+	return l.src[l.offset+ahead]
+}
+
+// advance moves over one byte and keeps the line bookkeeping.
+// "\n", "\r\n" and a lone "\r" all end a line.
+func (l *Lexer) advance() {
+	c := l.src[l.offset]
+	l.offset++
+	if c == '\n' || (c == '\r' && l.peek(0) != '\n') {
+		l.line++
+		l.lineStart = l.offset
+	}
+}
+
+func (l *Lexer) skipWhitespace() {
+	for l.offset < len(l.src) {
+		switch l.src[l.offset] {
+		case ' ', '\t', '\n', '\r', '\f', '\v':
+			l.advance()
+		default:
+			return
+		}
+	}
+}
+
+func (l *Lexer) errorf(start lsp.Position, format string, args ...any) {
+	l.Errors = append(l.Errors, SyntaxError{
+		Range: lsp.Range{Start: start, End: l.position()},
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Next returns the next token. Once the source is exhausted it keeps returning an EOF token.
+func (l *Lexer) Next() Token {
+	l.skipWhitespace()
+
+	start := l.position()
+	startOffset := l.offset
+	kind := l.scan(start)
+
+	return Token{
+		Kind: kind,
+		Value: l.src[startOffset:l.offset],
+		Start: start,
+		End: l.position(),
+		Offset: startOffset,
+	}
+}
+
+func (l *Lexer) scan(start lsp.Position) TokenKind {
+	if l.offset >= len(l.src) {
+		return TOKEN_EOF
+	}
+
+	startOffset := l.offset
+	c := l.src[l.offset]
+	switch {
+	case isIdentStart(c):
+		word := l.scanWord()
+		if IsKeyword(word) {
+			return TOKEN_KEYWORD
+		}
+		return TOKEN_IDENT
+	case isDigit(c) || (c == '.' && isDigit(l.peek(1))):
+		l.scanNumber()
+		return TOKEN_NUMBER
+	case c == '"':
+		l.scanString(start)
+		return TOKEN_STRING
+	case c == '/' && l.peek(1) == '/':
+		for l.offset < len(l.src) && l.src[l.offset] != '\n' && l.src[l.offset] != '\r' {
+			l.advance()
+		}
+		return TOKEN_COMMENT
+	case c == '/' && l.peek(1) == '*':
+		l.scanBlockComment(start)
+		return TOKEN_COMMENT
+	case c == '#' && isIdentStart(l.peek(1)):
+		l.advance()
+		l.scanWord()
+		return TOKEN_DIRECTIVE
+	}
+
+	for _, op := range operators {
+		if len(l.src)-l.offset >= len(op) && l.src[l.offset:l.offset+len(op)] == op {
+			for range len(op) {
+				l.advance()
+			}
+			return TOKEN_OPERATOR
+		}
+	}
+	for i := 0; i < len(punctuation); i++ {
+		if c == punctuation[i] {
+			l.advance()
+			return TOKEN_PUNCT
+		}
+	}
+
+	l.advance()
+	// keep multi-byte characters together so the illegal token is valid text
+	for l.offset < len(l.src) && l.src[l.offset]&0xC0 == 0x80 {
+		l.advance()
+	}
+	l.errorf(start, "unexpected character %q", l.src[startOffset:l.offset])
+	return TOKEN_ILLEGAL
+}
+
+func (l *Lexer) scanWord() string {
+	startOffset := l.offset
+	for l.offset < len(l.src) && isIdentPart(l.src[l.offset]) {
+		l.advance()
+	}
+	return l.src[startOffset:l.offset]
+}
+
+func (l *Lexer) scanNumber() {
+	if l.peek(0) == '0' && (l.peek(1) == 'x' || l.peek(1) == 'X') {
+		l.advance()
+		l.advance()
+		for isHexDigit(l.peek(0)) {
+			l.advance()
+		}
+		return
+	}
+	for isDigit(l.peek(0)) {
+		l.advance()
+	}
+	if l.peek(0) == '.' && isDigit(l.peek(1)) {
+		l.advance()
+		for isDigit(l.peek(0)) {
+			l.advance()
+		}
+	}
+	if (l.peek(0) == 'e' || l.peek(0) == 'E') &&
+	(isDigit(l.peek(1)) || ((l.peek(1) == '+' || l.peek(1) == '-') && isDigit(l.peek(2)))) {
+		l.advance()
+		l.advance()
+		for isDigit(l.peek(0)) {
+			l.advance()
+		}
+	}
+}
+
+// scanString reads a double quoted literal. Strings may not span lines;
+// an unterminated literal ends at the line break and is reported.
+func (l *Lexer) scanString(start lsp.Position) {
+	l.advance()
+	for l.offset < len(l.src) {
+		switch l.src[l.offset] {
+		case '"':
+			l.advance()
+			return
+		case '\\':
+			l.advance()
+			if l.offset < len(l.src) && l.src[l.offset] != '\n' && l.src[l.offset] != '\r' {
+				l.advance()
+			}
+		case '\n', '\r':
+			l.errorf(start, "unterminated string literal")
+			return
+		default:
+			l.advance()
+		}
+	}
+	l.errorf(start, "unterminated string literal")
+}
+
+func (l *Lexer) scanBlockComment(start lsp.Position) {
+	l.advance()
+	l.advance()
+	for l.offset < len(l.src) {
+		if l.src[l.offset] == '*' && l.peek(1) == '/' {
+			l.advance()
+			l.advance()
+			return
+		}
+		l.advance()
+	}
+	l.errorf(start, "unterminated block comment")
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
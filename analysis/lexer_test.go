@@ -0,0 +1,89 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestTokenizeCall(t *testing.T) {
+	tokens := analysis.Tokenize("\tMsgBox(\"Hello World!\", \"HelloWorld.sct\");")
+	expected := []struct {
+		kind analysis.TokenKind
+		value string
+	}{
+		{analysis.TOKEN_IDENT, "MsgBox"},
+		{analysis.TOKEN_PUNCT, "("},
+		{analysis.TOKEN_STRING, "\"Hello World!\""},
+		{analysis.TOKEN_PUNCT, ","},
+		{analysis.TOKEN_STRING, "\"HelloWorld.sct\""},
+		{analysis.TOKEN_PUNCT, ")"},
+		{analysis.TOKEN_PUNCT, ";"},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected: %d tokens, Actual: %d", len(expected), len(tokens))
+	}
+	for i, token := range tokens {
+		if token.Kind != expected[i].kind || token.Value != expected[i].value {
+			t.Fatalf("Expected: %s %s, Actual: %s %s", expected[i].kind, expected[i].value, token.Kind, token.Value)
+		}
+	}
+
+	str := tokens[2]
+	if str.Start != (lsp.Position{Line: 0, Character: 8}) || str.End != (lsp.Position{Line: 0, Character: 22}) {
+		t.Fatalf("Expected: 0:8-0:22, Actual: %v-%v", str.Start, str.End)
+	}
+}
+
+func TestTokenizePositions(t *testing.T) {
+	text := "#include \"file\"\r\n/* multi\r\nline */ long l = 0x1F;\r\n// done"
+	tokens := analysis.Tokenize(text)
+
+	expected := []struct {
+		kind analysis.TokenKind
+		start lsp.Position
+		end lsp.Position
+	}{
+		{analysis.TOKEN_DIRECTIVE, lsp.Position{Line: 0, Character: 0}, lsp.Position{Line: 0, Character: 8}},
+		{analysis.TOKEN_STRING, lsp.Position{Line: 0, Character: 9}, lsp.Position{Line: 0, Character: 15}},
+		{analysis.TOKEN_COMMENT, lsp.Position{Line: 1, Character: 0}, lsp.Position{Line: 2, Character: 7}},
+		{analysis.TOKEN_IDENT, lsp.Position{Line: 2, Character: 8}, lsp.Position{Line: 2, Character: 12}},
+		{analysis.TOKEN_IDENT, lsp.Position{Line: 2, Character: 13}, lsp.Position{Line: 2, Character: 14}},
+		{analysis.TOKEN_OPERATOR, lsp.Position{Line: 2, Character: 15}, lsp.Position{Line: 2, Character: 16}},
+		{analysis.TOKEN_NUMBER, lsp.Position{Line: 2, Character: 17}, lsp.Position{Line: 2, Character: 21}},
+		{analysis.TOKEN_PUNCT, lsp.Position{Line: 2, Character: 21}, lsp.Position{Line: 2, Character: 22}},
+		{analysis.TOKEN_COMMENT, lsp.Position{Line: 3, Character: 0}, lsp.Position{Line: 3, Character: 7}},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected: %d tokens, Actual: %d", len(expected), len(tokens))
+	}
+	for i, token := range tokens {
+		if token.Kind != expected[i].kind || token.Start != expected[i].start || token.End != expected[i].end {
+			t.Fatalf("Expected: %s %v-%v, Actual: %s %v-%v (%q)",
+				expected[i].kind, expected[i].start, expected[i].end,
+				token.Kind, token.Start, token.End, token.Value)
+		}
+		if text[token.Offset:token.EndOffset()] != token.Value {
+			t.Fatalf("Expected: %q, Actual: %q", token.Value, text[token.Offset:token.EndOffset()])
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	lexer := analysis.NewLexer("string s = \"open\n/* never closed")
+	for lexer.Next().Kind != analysis.TOKEN_EOF {
+	}
+	if len(lexer.Errors) != 2 {
+		t.Fatalf("Expected: 2 errors, Actual: %d", len(lexer.Errors))
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	expected := "say \"hi\"\n<BD>\\BIN\\Borm.sct"
+	actual := analysis.Unquote(`"say \"hi\"\n<BD>\BIN\\Borm.sct"`)
+	if expected != actual {
+		t.Fatalf("Expected: %q, Actual: %q", expected, actual)
+	}
+}
@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// MissingInclude is the use of a catalog function that lives in a script library
+// the file does not include.
+type MissingInclude struct {
+	Ident *Ident
+	Function *BormFunction
+}
+
+// Library is the file to include for a catalog entry, "" for entries of the runtime.
+// Library namespaces are written the way an include takes them, `<BD>\BIN\CTOMail.sct`.
+func (f *BormFunction) Library() string {
+	if !IsScript(f.Namespace) {
+		return ""
+	}
+	return f.Namespace
+}
+
+func (m MissingInclude) Problem() Problem {
+	return Problem{
+		Range: NodeRange(m.Ident),
+		Severity: SEVERITY_ERROR,
+		Message: fmt.Sprintf("'%s' is declared in '%s', which is not included", m.Ident.Name, m.Function.Library()),
+	}
+}
+
+// libraryName is what a library is known by, includes find files regardless of case and directory.
+func libraryName(path string) string {
+	return strings.ToLower(filepath.Base(filepath.FromSlash(strings.ReplaceAll(path, "\\", "/"))))
+}
+
+// MissingIncludes finds the uses of catalog functions from libraries that a file neither is
+// nor includes, directly or transitively. Includes that cannot be resolved count as well,
+// they are reported on their own.
+func (w *Workspace) MissingIncludes(uri string) []MissingInclude {
+	missing := []MissingInclude{}
+	file, ok := w.Files[uri]
+	if !ok {
+		return missing
+	}
+	included := map[string]bool{libraryName(URIToPath(uri)): true}
+	for _, include := range file.Includes {
+		included[libraryName(include.Directive.Path)] = true
+	}
+	for _, other := range w.IncludedFiles(uri) {
+		included[libraryName(URIToPath(other))] = true
+	}
+
+	Inspect(file.Tree.File, func(node Node) bool {
+		ident, ok := node.(*Ident)
+		if !ok {
+			return true
+		}
+		symbol, ok := file.Resolution.Symbols[ident]
+		if !ok || symbol.Kind != SYMBOL_BUILTIN {
+			return true
+		}
+		if library := symbol.Builtin.Library(); library != "" && !included[libraryName(library)] {
+			missing = append(missing, MissingInclude{Ident: ident, Function: symbol.Builtin})
+		}
+		return true
+	})
+	return missing
+}
+
+// Includable reports whether an include of the library written in the file would find it.
+// Some namespaces of the catalog name no file, like NOT_SUPPORTED.sct.
+func (w *Workspace) Includable(uri, library string) bool {
+	dir := ""
+	if path := URIToPath(uri); path != "" {
+		dir = filepath.Dir(path)
+	}
+	_, err := findInclude(&IncludeDirective{Path: library}, dir, w.Config)
+	return err == nil
+}
+
+// IncludeInsertLine is where a new include goes: at the top of the file, below the comments
+// the file starts with.
+func IncludeInsertLine(file *File) int {
+	line := 0
+	for _, comment := range file.Comments {
+		if comment.Slash.Line != line || comment.Slash.Character != 0 {
+			break
+		}
+		line = comment.Stop.Line + 1
+	}
+	if len(file.Decls) > 0 {
+		// a comment ending on the line of the first declaration is not above it
+		line = min(line, file.Decls[0].Pos().Line)
+	}
+	return line
+}
@@ -0,0 +1,81 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"path/filepath"
+	"testing"
+)
+
+func TestMissingInclude(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "#include \"BGLagerfuehrungEx.sct\"\n",
+		"BGLagerfuehrungEx.sct": "",
+	},
+		analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text", ""),
+		analysis.NewBormFunction("Mail", "<BD>\\BIN\\CTOMail.sct", "void", "CTOMailAddATTACHMENT", "string __filename", ""),
+		analysis.NewBormFunction("LagerfuehrungEx", "BGLagerfuehrungEx.sct", "bool", "BGLFTS_WABook", "", ""),
+		analysis.NewBormFunction("Alt", "NOT_SUPPORTED.sct", "void", "OldFunction", "", ""),
+	)
+	base := t.TempDir()
+	writeScript(t, filepath.Join(base, "BIN", "CTOMail.sct"), "")
+	state.ChangeConfiguration(logger, &lsp.Settings{BaseDirectory: base})
+	main := scriptURI(state, "main.sct")
+	text := "#include \"lib.sct\"\n" +
+		"void function main() {\n" +
+		"\tMsgBox(\"x\");\n" +
+		"\tCTOMailAddATTACHMENT(\"a\");\n" +
+		"\tCTOMailAddATTACHMENT(\"b\");\n" +
+		"\tBGLFTS_WABook();\n" +
+		"\tOldFunction();\n" +
+		"}\n"
+	all := state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+	diagnostics := all[0].Diagnostics
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected: 3 diagnostics, Actual: %v", diagnostics)
+	}
+	message := "'CTOMailAddATTACHMENT' is declared in '<BD>\\BIN\\CTOMail.sct', which is not included"
+	if diagnostics[0].Message != message || diagnostics[0].Range.Start.Line != 3 {
+		t.Fatalf("Expected: %s on line 3, Actual: %v", message, diagnostics[0])
+	}
+
+	actions := state.CodeAction(1, lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: main},
+		Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 0}, End: lsp.Position{Line: 7, Character: 0}},
+	}).Result
+	// NOT_SUPPORTED.sct is no file to include
+	if len(actions) != 1 || len(actions[0].Diagnostics) != 2 {
+		t.Fatalf("Expected: 1 action fixing both calls, Actual: %v", actions)
+	}
+	edits := actions[0].Edit.Changes[main]
+	if len(edits) != 1 || edits[0].Range.Start.Line != 0 || edits[0].NewText != "#include \"<BD>\\BIN\\CTOMail.sct\"\n" {
+		t.Fatalf("Expected: include inserted on line 0, Actual: %v", edits)
+	}
+
+	actions = state.CodeAction(2, lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: main},
+		Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 1}, End: lsp.Position{Line: 2, Character: 1}},
+	}).Result
+	if len(actions) != 0 {
+		t.Fatalf("Expected: no action for MsgBox, Actual: %v", actions)
+	}
+}
+
+func TestIncludeInsertLine(t *testing.T) {
+	tests := []struct {
+		text string
+		line int
+	}{
+		{"void function f() {\n}\n", 0},
+		{"#include \"lib.sct\"\nvoid function f() {\n}\n", 0},
+		{"// Mail helpers\n/* written\n   by hand */\n\n#include \"lib.sct\"\n", 3},
+		{"// Mail helpers\n\n// f\nvoid function f() {\n}\n", 1},
+		{"/* f */ void function f() {\n}\n", 0},
+	}
+	for _, test := range tests {
+		file, _ := analysis.Parse("test.sct", test.text)
+		if line := analysis.IncludeInsertLine(file); line != test.line {
+			t.Fatalf("Expected: %d in %q, Actual: %d", test.line, test.text, line)
+		}
+	}
+}
@@ -0,0 +1,769 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"fmt"
+	"sort"
+)
+
+type Parser struct {
+	tokens []Token
+	pos int
+	Comments []Token
+	Errors []SyntaxError
+	lexErrors []SyntaxError
+	lastErrorOffset int
+	// horizon is the index of the furthest token looked at, see declInfo.
+	horizon int
+	// prototypes allows functions without a body, only the generated catalog declares them.
+	prototypes bool
+}
+
+func NewParser(text string) *Parser {
+	p := &Parser{lastErrorOffset: -1}
+	lexer := NewLexer(text)
+	for {
+		token := lexer.Next()
+		if token.IsComment() {
+			p.Comments = append(p.Comments, token)
+			continue
+		}
+		p.tokens = append(p.tokens, token)
+		if token.Kind == TOKEN_EOF {
+			break
+		}
+	}
+	p.lexErrors = lexer.Errors
+	p.prototypes = len(p.Comments) > 0 && p.Comments[0].Offset == 0 && p.Comments[0].Value == CATALOG_HEADER
+	return p
+}
+
+// Parse builds the syntax tree of a whole script.
+// Syntax errors do not stop the parser, every error found is returned sorted by position.
+func Parse(uri, text string) (*File, []SyntaxError) {
+	p := NewParser(text)
+	file := p.parseFile(uri)
+
+	errors := append(p.lexErrors, p.Errors...)
+	sort.SliceStable(errors, func(i, j int) bool {
+		return PositionLess(errors[i].Range.Start, errors[j].Range.Start)
+	})
+	return file, errors
+}
+
+func (p *Parser) peek() Token {
+	return p.peekAt(0)
+}
+
+func (p *Parser) peekAt(ahead int) Token {
+	index := min(p.pos+ahead, len(p.tokens)-1)
+	p.horizon = max(p.horizon, index)
+	return p.tokens[index]
+}
+
+func (p *Parser) next() Token {
+	token := p.peek()
+	if token.Kind != TOKEN_EOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *Parser) previous() Token {
+	if p.pos == 0 {
+		return p.tokens[0]
+	}
+	return p.tokens[p.pos-1]
+}
+
+func (p *Parser) at(kind TokenKind, value string) bool {
+	return p.peek().Is(kind, value)
+}
+
+func (p *Parser) atPunct(value string) bool {
+	return p.at(TOKEN_PUNCT, value)
+}
+
+func (p *Parser) atEOF() bool {
+	return p.peek().Kind == TOKEN_EOF
+}
+
+func (p *Parser) accept(kind TokenKind, value string) bool {
+	if p.at(kind, value) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func describe(token Token) string {
+	if token.Kind == TOKEN_EOF {
+		return "end of file"
+	}
+	value := token.Value
+	if len(value) > 24 {
+		value = value[:21] + "..."
+	}
+	return fmt.Sprintf("'%s'", value)
+}
+
+// errorAt records a syntax error on the token. Only the first error at a position is kept,
+// everything reported at the same place afterwards is a consequence of it.
+func (p *Parser) errorAt(token Token, format string, args ...any) {
+	if token.Offset == p.lastErrorOffset {
+		return
+	}
+	p.lastErrorOffset = token.Offset
+	end := token.End
+	if token.Kind == TOKEN_EOF {
+		end = token.Start
+	}
+	p.Errors = append(p.Errors, SyntaxError{
+		Range: lsp.Range{Start: token.Start, End: end},
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// expected reports what was expected at the current token, e.g. "expected ')' after parameter list, found '{'".
+func (p *Parser) expected(what string) {
+	p.errorAt(p.peek(), "expected %s, found %s", what, describe(p.peek()))
+}
+
+// expect consumes the wanted token. When it is missing nothing is consumed
+// and an error naming the token and its context is reported.
+func (p *Parser) expect(kind TokenKind, value, context string) (Token, bool) {
+	token := p.peek()
+	if token.Is(kind, value) {
+		p.next()
+		return token, true
+	}
+	p.expected(fmt.Sprintf("'%s' %s", value, context))
+	return token, false
+}
+
+// atDeclarationStart reports whether a top level declaration can start at the current token.
+func (p *Parser) atDeclarationStart() bool {
+	return p.peek().Kind == TOKEN_DIRECTIVE || p.looksLikeFunction()
+}
+
+// syncDeclaration skips tokens until the parser is back on top level:
+// at an include, a function declaration, or right behind a `;` or `}` outside of any block.
+func (p *Parser) syncDeclaration() {
+	depth := 0
+	for !p.atEOF() {
+		if depth == 0 && p.atDeclarationStart() {
+			return
+		}
+		token := p.next()
+		switch {
+		case token.Is(TOKEN_PUNCT, "{"):
+			depth++
+		case token.Is(TOKEN_PUNCT, "}"):
+			if depth > 0 {
+				depth--
+			}
+			if depth == 0 {
+				return
+			}
+		case token.Is(TOKEN_PUNCT, ";") && depth == 0:
+			return
+		}
+	}
+}
+
+// syncStatement skips the rest of a broken statement. It stops behind a `;`,
+// or in front of the `}` closing the current block or the next function declaration.
+func (p *Parser) syncStatement() {
+	depth := 0
+	for !p.atEOF() {
+		token := p.peek()
+		switch {
+		case token.Is(TOKEN_PUNCT, ";") && depth == 0:
+			p.next()
+			return
+		case token.Is(TOKEN_PUNCT, "}"):
+			if depth == 0 {
+				return
+			}
+			depth--
+		case token.Is(TOKEN_PUNCT, "{"):
+			depth++
+		case depth == 0 && p.looksLikeFunction():
+			return
+		}
+		p.next()
+	}
+}
+
+func (p *Parser) parseFile(uri string) *File {
+	file := p.newFile(uri)
+	for !p.atEOF() {
+		p.parseTopLevel(file)
+	}
+	return file
+}
+
+// newFile creates the file node spanning all tokens, with the comments but without declarations.
+func (p *Parser) newFile(uri string) *File {
+	first, last := p.tokens[0], p.tokens[len(p.tokens)-1]
+	file := &File{URI: uri, Start: first.Start, Stop: last.End}
+	if len(p.Comments) > 0 && PositionLess(p.Comments[0].Start, file.Start) {
+		file.Start = p.Comments[0].Start
+	}
+	for _, comment := range p.Comments {
+		file.Comments = append(file.Comments, &Comment{Slash: comment.Start, Text: comment.Value, Stop: comment.End})
+	}
+	return file
+}
+
+// parseTopLevel parses one top level declaration and records what Reparse needs to know about it.
+func (p *Parser) parseTopLevel(file *File) {
+	start := p.pos
+	p.horizon = p.pos
+	errors := len(p.Errors)
+	file.Decls = append(file.Decls, p.parseDeclaration())
+	if p.pos == start {
+		// make progress no matter what
+		p.next()
+	}
+
+	info := declInfo{
+		start: start,
+		end: p.pos,
+		offset: p.tokens[start].Offset,
+		horizon: p.tokens[p.horizon].EndOffset(),
+		errors: append([]SyntaxError{}, p.Errors[errors:]...),
+		lastError: -1,
+	}
+	if p.lastErrorOffset >= p.tokens[p.pos].Offset {
+		info.lastError = p.lastErrorOffset
+	}
+	file.decls = append(file.decls, info)
+}
+
+func (p *Parser) parseDeclaration() Decl {
+	token := p.peek()
+	switch {
+	case token.Kind == TOKEN_DIRECTIVE:
+		return p.parseDirective()
+	case p.looksLikeFunction():
+		return p.parseFunction()
+	case p.looksLikeDeclaration():
+		decl := p.parseVarDecl()
+		if !p.expectSemicolon(&decl.Stop, "after global variable declaration") {
+			p.syncDeclaration()
+		}
+		return decl
+	}
+	p.expected("function or variable declaration")
+	p.syncDeclaration()
+	return &BadDecl{From: token.Start, To: p.previous().End}
+}
+
+// parseDirective reads `#include "file"` or `#include <file>`. The directive ends at the line break.
+func (p *Parser) parseDirective() Decl {
+	directive := p.next()
+	node := &IncludeDirective{Hash: directive.Start, PathStart: directive.End, PathEnd: directive.End}
+
+	onLine := func() bool {
+		return !p.atEOF() && p.peek().Start.Line == directive.Start.Line
+	}
+	skipLine := func() lsp.Position {
+		for onLine() {
+			p.next()
+		}
+		return p.previous().End
+	}
+
+	if directive.Value != "#include" {
+		p.errorAt(directive, "unknown directive '%s'", directive.Value)
+		return &BadDecl{From: directive.Start, To: skipLine()}
+	}
+	if !onLine() {
+		p.errorAt(directive, "expected file name after #include, found end of line")
+		return node
+	}
+
+	token := p.next()
+	node.PathStart, node.PathEnd = token.Start, token.End
+	switch {
+	case token.Kind == TOKEN_STRING:
+		node.Path = Unquote(token.Value)
+	case token.Is(TOKEN_OPERATOR, "<"):
+		node.Angled = true
+		// the file name is lexed as several tokens (dots, separators), so glue them back together
+		for onLine() && !p.at(TOKEN_OPERATOR, ">") {
+			node.Path += p.next().Value
+		}
+		if p.at(TOKEN_OPERATOR, ">") {
+			node.PathEnd = p.next().End
+		} else {
+			p.expected("'>' to close include path")
+			node.PathEnd = p.previous().End
+		}
+		if node.Path == "" {
+			p.errorAt(token, "include path must not be empty")
+		}
+	default:
+		p.errorAt(token, "expected \"file\" or <file> after #include, found %s", describe(token))
+	}
+
+	if onLine() {
+		p.errorAt(p.peek(), "unexpected %s after include path", describe(p.peek()))
+		skipLine()
+	}
+	return node
+}
+
+// looksLikeFunction reports whether the upcoming tokens read `<type> function`.
+// A lone `function` counts as well, the missing type is reported while parsing.
+func (p *Parser) looksLikeFunction() bool {
+	if p.at(TOKEN_KEYWORD, "function") {
+		return true
+	}
+	save := p.pos
+	defer func() { p.pos = save }()
+	if p.parseType() == nil {
+		return false
+	}
+	return p.at(TOKEN_KEYWORD, "function")
+}
+
+// looksLikeDeclaration reports whether the upcoming tokens read `<type> <name>`.
+func (p *Parser) looksLikeDeclaration() bool {
+	save := p.pos
+	defer func() { p.pos = save }()
+	if p.parseType() == nil {
+		return false
+	}
+	return p.peek().Kind == TOKEN_IDENT
+}
+
+// parseType reads `name`, `name<type, ...>` or `[]type`. It reports nothing and returns nil
+// on failure, so it can be used to look ahead; callers report a failure in their own context.
+func (p *Parser) parseType() *TypeExpr {
+	start := p.peek()
+	if p.atPunct("[") {
+		p.next()
+		if !p.atPunct("]") {
+			return nil
+		}
+		p.next()
+		elem := p.parseType()
+		if elem == nil {
+			return nil
+		}
+		return &TypeExpr{Start: start.Start, Args: []*TypeExpr{elem}, Array: true, Stop: elem.Stop}
+	}
+	if start.Kind != TOKEN_IDENT {
+		return nil
+	}
+	p.next()
+	typ := &TypeExpr{Start: start.Start, Name: start.Value, Stop: start.End}
+	if !p.at(TOKEN_OPERATOR, "<") {
+		return typ
+	}
+	p.next()
+	for {
+		arg := p.parseType()
+		if arg == nil {
+			return nil
+		}
+		typ.Args = append(typ.Args, arg)
+		if !p.atPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if !p.at(TOKEN_OPERATOR, ">") {
+		return nil
+	}
+	typ.Stop = p.next().End
+	return typ
+}
+
+// parseIdent reads a name. A missing name is reported and an empty Ident is returned in its place.
+func (p *Parser) parseIdent(what string) *Ident {
+	token := p.peek()
+	if token.Kind != TOKEN_IDENT {
+		p.expected(what)
+		return &Ident{NamePos: token.Start}
+	}
+	p.next()
+	return &Ident{NamePos: token.Start, Name: token.Value}
+}
+
+func (p *Parser) parseFunction() Decl {
+	start := p.peek()
+	decl := &FuncDecl{}
+	if start.Is(TOKEN_KEYWORD, "function") {
+		p.errorAt(start, "expected return type before 'function'")
+	} else {
+		decl.Type = p.parseType()
+	}
+	decl.Func = p.next().Start
+	decl.Name = p.parseIdent("function name after 'function'")
+
+	if !p.atPunct("(") {
+		p.expected("'(' after function name")
+		decl.Lparen, decl.Rparen = p.peek().Start, p.peek().Start
+		if !p.atPunct("{") {
+			p.syncDeclaration()
+			decl.Stop = p.previous().End
+			return decl
+		}
+	} else {
+		decl.Lparen = p.next().Start
+		decl.Params, decl.Rparen = p.parseParams()
+	}
+
+	if p.prototypes && p.atPunct(";") {
+		decl.Stop = p.next().End
+		return decl
+	}
+	if !p.atPunct("{") {
+		p.expected("'{' to start function body")
+		p.syncDeclaration()
+		decl.Stop = p.previous().End
+		return decl
+	}
+	decl.Body = p.parseBlock()
+	decl.Stop = decl.Body.Stop
+	return decl
+}
+
+// parseParams reads the parameters behind the opening parenthesis, including the closing one.
+func (p *Parser) parseParams() ([]*Param, lsp.Position) {
+	params := []*Param{}
+	if p.atPunct(")") {
+		return params, p.next().Start
+	}
+	for {
+		if param := p.parseParam(); param != nil {
+			params = append(params, param)
+		} else {
+			// skip the broken parameter, but never past the function body
+			for !p.atEOF() && !p.atPunct(",") && !p.atPunct(")") && !p.atPunct("{") && !p.atPunct(";") {
+				p.next()
+			}
+		}
+		if !p.accept(TOKEN_PUNCT, ",") {
+			break
+		}
+	}
+	rparen, _ := p.expect(TOKEN_PUNCT, ")", "after parameter list")
+	return params, rparen.Start
+}
+
+func (p *Parser) parseParam() *Param {
+	typ := p.parseType()
+	if typ == nil {
+		p.expected("parameter type")
+		return nil
+	}
+	byRef := p.accept(TOKEN_OPERATOR, "&")
+	name := p.parseIdent(fmt.Sprintf("parameter name after type '%s'", typ))
+	if name.Name == "" {
+		return nil
+	}
+	return &Param{Type: typ, ByRef: byRef, Name: name}
+}
+
+// parseVarDecl reads `<type> <name>` with an optional initializer, without the closing semicolon.
+func (p *Parser) parseVarDecl() *VarDecl {
+	typ := p.parseType()
+	decl := &VarDecl{Type: typ}
+	decl.Name = p.parseIdent(fmt.Sprintf("variable name after type '%s'", typ))
+	decl.Stop = decl.Name.End()
+	if p.accept(TOKEN_OPERATOR, "=") {
+		decl.Value = p.parseExpression()
+		decl.Stop = decl.Value.End()
+	}
+	return decl
+}
+
+// expectSemicolon closes a statement and moves stop behind it. It reports whether the parser
+// can simply go on: a semicolon missing at the end of a line only needs to be reported,
+// anything else on the same line has to be skipped by the caller.
+func (p *Parser) expectSemicolon(stop *lsp.Position, context string) bool {
+	if semicolon, ok := p.expect(TOKEN_PUNCT, ";", context); ok {
+		*stop = semicolon.End
+		return true
+	}
+	return p.peek().Start.Line > p.previous().End.Line
+}
+
+func (p *Parser) parseBlock() *BlockStmt {
+	lbrace := p.next()
+	block := &BlockStmt{Lbrace: lbrace.Start, List: []Stmt{}}
+	for !p.atPunct("}") && !p.atEOF() {
+		if p.looksLikeFunction() {
+			// a function declaration can only follow a block that was never closed
+			break
+		}
+		before := p.pos
+		stmt := p.parseStatement()
+		if p.pos == before {
+			p.syncStatement()
+			if p.pos == before {
+				p.next()
+			}
+			stmt = &BadStmt{From: p.tokens[before].Start, To: p.previous().End}
+		}
+		block.List = append(block.List, stmt)
+	}
+	if rbrace, ok := p.expect(TOKEN_PUNCT, "}", "to close block"); ok {
+		block.Stop = rbrace.End
+	} else {
+		block.Stop = p.previous().End
+	}
+	return block
+}
+
+func (p *Parser) parseStatement() Stmt {
+	token := p.peek()
+	switch {
+	case token.Is(TOKEN_PUNCT, "{"):
+		return p.parseBlock()
+	case token.Is(TOKEN_PUNCT, ";"):
+		p.next()
+		return &EmptyStmt{Semicolon: token.Start}
+	case token.Is(TOKEN_KEYWORD, "if"):
+		return p.parseIf()
+	case token.Is(TOKEN_KEYWORD, "while"):
+		return p.parseWhile()
+	case token.Is(TOKEN_KEYWORD, "for"):
+		return p.parseFor()
+	case token.Is(TOKEN_KEYWORD, "else"):
+		p.errorAt(token, "'else' without matching 'if'")
+		p.next()
+		return p.parseStatement()
+	case token.Is(TOKEN_KEYWORD, "return"):
+		p.next()
+		stmt := &ReturnStmt{Return: token.Start, Stop: token.End}
+		if !p.atPunct(";") && !p.atPunct("}") {
+			stmt.Result = p.parseExpression()
+			stmt.Stop = stmt.Result.End()
+		}
+		p.endStatement(&stmt.Stop, "after return statement")
+		return stmt
+	case token.Is(TOKEN_KEYWORD, "break"), token.Is(TOKEN_KEYWORD, "continue"):
+		p.next()
+		stmt := &BranchStmt{TokPos: token.Start, Tok: token.Value, Stop: token.End}
+		p.endStatement(&stmt.Stop, fmt.Sprintf("after '%s'", token.Value))
+		return stmt
+	}
+
+	switch stmt := p.parseSimpleStatement().(type) {
+	case *VarDecl:
+		p.endStatement(&stmt.Stop, "after variable declaration")
+		return stmt
+	case *ExprStmt:
+		p.endStatement(&stmt.Stop, "after expression")
+		return stmt
+	default:
+		return stmt
+	}
+}
+
+func (p *Parser) endStatement(stop *lsp.Position, context string) {
+	if !p.expectSemicolon(stop, context) {
+		p.syncStatement()
+	}
+}
+
+// parseSimpleStatement reads a declaration or an expression, as allowed in the head of a for loop.
+func (p *Parser) parseSimpleStatement() Stmt {
+	if p.looksLikeDeclaration() {
+		return p.parseVarDecl()
+	}
+	expr := p.parseExpression()
+	return &ExprStmt{X: expr, Stop: expr.End()}
+}
+
+// parseCondition reads `(expr)`. A missing parenthesis is reported but the condition is still parsed.
+func (p *Parser) parseCondition(keyword string) Expr {
+	p.expect(TOKEN_PUNCT, "(", fmt.Sprintf("after '%s'", keyword))
+	cond := p.parseExpression()
+	p.expect(TOKEN_PUNCT, ")", "after condition")
+	return cond
+}
+
+func (p *Parser) parseIf() Stmt {
+	stmt := &IfStmt{If: p.next().Start}
+	stmt.Cond = p.parseCondition("if")
+	stmt.Body = p.parseStatement()
+	if p.accept(TOKEN_KEYWORD, "else") {
+		stmt.Else = p.parseStatement()
+	}
+	return stmt
+}
+
+func (p *Parser) parseWhile() Stmt {
+	stmt := &WhileStmt{While: p.next().Start}
+	stmt.Cond = p.parseCondition("while")
+	stmt.Body = p.parseStatement()
+	return stmt
+}
+
+// parseFor reads `for (init; cond; post) body`, every part of the head is optional.
+func (p *Parser) parseFor() Stmt {
+	start := p.next()
+	stmt := &ForStmt{For: start.Start}
+	if _, ok := p.expect(TOKEN_PUNCT, "(", "after 'for'"); !ok {
+		p.syncStatement()
+		stmt.Body = &BadStmt{From: start.Start, To: p.previous().End}
+		return stmt
+	}
+	if !p.atPunct(";") {
+		stmt.Init = p.parseSimpleStatement()
+	}
+	p.expect(TOKEN_PUNCT, ";", "after loop initializer")
+	if !p.atPunct(";") {
+		stmt.Cond = p.parseExpression()
+	}
+	p.expect(TOKEN_PUNCT, ";", "after loop condition")
+	if !p.atPunct(")") {
+		stmt.Post = p.parseSimpleStatement()
+	}
+	p.expect(TOKEN_PUNCT, ")", "after loop increment")
+	stmt.Body = p.parseStatement()
+	return stmt
+}
+
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|": 3,
+	"&": 4,
+	"==": 5, "!=": 5,
+	"<": 6, "<=": 6, ">": 6, ">=": 6,
+	"+": 7, "-": 7,
+	"*": 8, "/": 8, "%": 8,
+}
+
+var assignOperators = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true,
+}
+
+func isBad(expr Expr) bool {
+	_, bad := expr.(*BadExpr)
+	return bad
+}
+
+func (p *Parser) parseExpression() Expr {
+	target := p.parseBinary(1)
+	token := p.peek()
+	if isBad(target) || token.Kind != TOKEN_OPERATOR || !assignOperators[token.Value] {
+		return target
+	}
+	p.next()
+	// assignments are right associative
+	value := p.parseExpression()
+	return &AssignExpr{Target: target, OpPos: token.Start, Op: token.Value, Value: value}
+}
+
+// parseBinary climbs the precedence table, only binding operators of at least minPrec.
+func (p *Parser) parseBinary(minPrec int) Expr {
+	left := p.parseUnary()
+	for !isBad(left) {
+		token := p.peek()
+		prec, ok := binaryPrecedence[token.Value]
+		if token.Kind != TOKEN_OPERATOR || !ok || prec < minPrec {
+			return left
+		}
+		p.next()
+		right := p.parseBinary(prec+1)
+		left = &BinaryExpr{X: left, OpPos: token.Start, Op: token.Value, Y: right}
+	}
+	return left
+}
+
+func (p *Parser) parseUnary() Expr {
+	token := p.peek()
+	if token.Kind == TOKEN_OPERATOR {
+		switch token.Value {
+		case "!", "-", "+", "++", "--":
+			p.next()
+			return &UnaryExpr{OpPos: token.Start, Op: token.Value, X: p.parseUnary()}
+		}
+	}
+	primary := p.parsePrimary()
+	if isBad(primary) {
+		return primary
+	}
+	return p.parsePostfix(primary)
+}
+
+func (p *Parser) parsePostfix(expr Expr) Expr {
+	for {
+		token := p.peek()
+		switch {
+		case token.Is(TOKEN_PUNCT, "("):
+			p.next()
+			call := &CallExpr{Fun: expr, Lparen: token.Start}
+			p.parseArguments(call)
+			expr = call
+		case token.Is(TOKEN_PUNCT, "["):
+			p.next()
+			index := &IndexExpr{X: expr, Lbrack: token.Start, Index: p.parseExpression()}
+			index.Stop = index.Index.End()
+			if rbrack, ok := p.expect(TOKEN_PUNCT, "]", "after index"); ok {
+				index.Stop = rbrack.End
+			}
+			expr = index
+		case token.Is(TOKEN_PUNCT, "."):
+			p.next()
+			expr = &MemberExpr{X: expr, Member: p.parseIdent("member name after '.'")}
+		case token.Is(TOKEN_OPERATOR, "++"), token.Is(TOKEN_OPERATOR, "--"):
+			p.next()
+			expr = &UnaryExpr{OpPos: token.Start, Op: token.Value, X: expr, Postfix: true}
+		default:
+			return expr
+		}
+	}
+}
+
+// parseArguments reads the arguments behind the opening parenthesis, including the closing one.
+func (p *Parser) parseArguments(call *CallExpr) {
+	call.Args = []Expr{}
+	if !p.atPunct(")") {
+		for {
+			arg := p.parseExpression()
+			call.Args = append(call.Args, arg)
+			if isBad(arg) || !p.accept(TOKEN_PUNCT, ",") {
+				break
+			}
+		}
+	}
+	if rparen, ok := p.expect(TOKEN_PUNCT, ")", "after arguments"); ok {
+		call.Rparen, call.Stop = rparen.Start, rparen.End
+	} else {
+		call.Rparen, call.Stop = p.peek().Start, p.previous().End
+	}
+}
+
+func (p *Parser) parsePrimary() Expr {
+	token := p.peek()
+	switch {
+	case token.Kind == TOKEN_IDENT:
+		return p.parseIdent("identifier")
+	case token.Kind == TOKEN_NUMBER, token.Kind == TOKEN_STRING,
+	token.Is(TOKEN_KEYWORD, "true"), token.Is(TOKEN_KEYWORD, "false"):
+		p.next()
+		return &BasicLit{ValuePos: token.Start, Kind: token.Kind, Value: token.Value}
+	case token.Is(TOKEN_KEYWORD, "callback"):
+		p.next()
+		return &CallbackExpr{Callback: token.Start, Name: p.parseIdent("function name after 'callback'")}
+	case token.Is(TOKEN_PUNCT, "("):
+		p.next()
+		paren := &ParenExpr{Lparen: token.Start, X: p.parseExpression()}
+		paren.Stop = paren.X.End()
+		if rparen, ok := p.expect(TOKEN_PUNCT, ")", "to close parenthesized expression"); ok {
+			paren.Stop = rparen.End
+		}
+		return paren
+	}
+	p.expected("expression")
+	return &BadExpr{From: token.Start, To: token.End}
+}
@@ -0,0 +1,161 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseFunction(t *testing.T) {
+	text := "#include \"file\"\nbool function main(long a, list<string> b) {\n\tlong l = a + 2 * 3;\n\tif (l > 0) { MsgBox(\"Hello World!\", \"HelloWorld.sct\"); } else return false;\n\treturn true;\n}"
+	file, errors := analysis.Parse("test.sct", text)
+
+	if len(errors) != 0 {
+		t.Fatalf("Expected: no errors, Actual: %v", errors)
+	}
+	if len(file.Decls) != 2 {
+		t.Fatalf("Expected: 2 declarations, Actual: %d", len(file.Decls))
+	}
+	include, ok := file.Decls[0].(*analysis.IncludeDirective)
+	if !ok || include.Path != "file" {
+		t.Fatalf("Expected: include of file, Actual: %#v", file.Decls[0])
+	}
+
+	function, ok := file.Decls[1].(*analysis.FuncDecl)
+	if !ok || function.Name.Name != "main" || function.Type.String() != "bool" {
+		t.Fatalf("Expected: bool function main, Actual: %#v", file.Decls[1])
+	}
+	if len(function.Params) != 2 || function.Params[1].Type.String() != "list<string>" {
+		t.Fatalf("Expected: 2 parameters, the second a list<string>, Actual: %d", len(function.Params))
+	}
+
+	decl, ok := function.Body.List[0].(*analysis.VarDecl)
+	if !ok || decl.Value == nil {
+		t.Fatalf("Expected: variable with initializer, Actual: %#v", function.Body.List[0])
+	}
+	sum := decl.Value.(*analysis.BinaryExpr)
+	if sum.Op != "+" || sum.Y.(*analysis.BinaryExpr).Op != "*" {
+		t.Fatalf("Expected: a + (2 * 3), Actual: %s", sum.Op)
+	}
+
+	ifStmt, ok := function.Body.List[1].(*analysis.IfStmt)
+	if !ok || ifStmt.Else == nil {
+		t.Fatalf("Expected: if with else, Actual: %#v", function.Body.List[1])
+	}
+	call := ifStmt.Body.(*analysis.BlockStmt).List[0].(*analysis.ExprStmt).X.(*analysis.CallExpr)
+	if call.Fun.(*analysis.Ident).Name != "MsgBox" || len(call.Args) != 2 {
+		t.Fatalf("Expected: MsgBox with 2 arguments, Actual: %d arguments", len(call.Args))
+	}
+	if call.Pos().Line != 3 || call.Pos().Character != 14 || call.End().Character != 54 {
+		t.Fatalf("Expected: 3:14-3:54, Actual: %v-%v", call.Pos(), call.End())
+	}
+}
+
+func TestParseMissingSemicolon(t *testing.T) {
+	_, errors := analysis.Parse("test.sct", "bool function f() {\n\treturn true\n}")
+	if len(errors) != 1 {
+		t.Fatalf("Expected: an error for the missing semicolon, Actual: %v", errors)
+	}
+}
+
+func TestParseRecovers(t *testing.T) {
+	text := `bool function f(long a {
+	long x = ;
+	return true
+}
+
+long function g(string s) {
+	if (s == "") {
+		return 1 2;
+	}
+	return 0;
+}
+
+string function h() {
+	return [];
+}`
+	_, errors := analysis.Parse("test.sct", text)
+
+	expected := []string{
+		"1:23 expected ')' after parameter list, found '{'",
+		"2:10 expected expression, found ';'",
+		"4:0 expected ';' after return statement, found '}'",
+		"8:11 expected ';' after return statement, found '2'",
+		"14:8 expected expression, found '['",
+	}
+	if len(errors) != len(expected) {
+		t.Fatalf("Expected: %d errors, Actual: %d %v", len(expected), len(errors), errors)
+	}
+	for i, err := range errors {
+		actual := fmt.Sprintf("%d:%d %s", err.Range.Start.Line+1, err.Range.Start.Character, err.Message)
+		if actual != expected[i] {
+			t.Fatalf("Expected: %s, Actual: %s", expected[i], actual)
+		}
+	}
+}
+
+func TestParsePrototype(t *testing.T) {
+	text := "long function f(long a);\n"
+	if _, errors := analysis.Parse("test.sct", text); len(errors) != 1 || errors[0].Message != "expected '{' to start function body, found ';'" {
+		t.Fatalf("Expected: an error for the missing body, Actual: %v", errors)
+	}
+	file, errors := analysis.Parse("catalog.sct", analysis.CATALOG_HEADER+"\n"+text)
+	if len(errors) != 0 || file.Decls[0].(*analysis.FuncDecl).Body != nil {
+		t.Fatalf("Expected: the catalog declares f without a body, Actual: %v", errors)
+	}
+}
+
+func TestParseUnclosedFunction(t *testing.T) {
+	text := "bool function f() {\n\tif (true) {\n\t\treturn true;\n}\n\nbool function g() {\n\treturn false;\n}"
+	file, errors := analysis.Parse("test.sct", text)
+
+	if len(errors) != 1 || errors[0].Message != "expected '}' to close block, found 'bool'" {
+		t.Fatalf("Expected: one error for the missing brace, Actual: %v", errors)
+	}
+	if len(file.Decls) != 2 || file.Decls[1].(*analysis.FuncDecl).Name.Name != "g" {
+		t.Fatalf("Expected: function g to be parsed, Actual: %d declarations", len(file.Decls))
+	}
+}
+
+func TestInspect(t *testing.T) {
+	file, _ := analysis.Parse("test.sct", "long function f(long a) {\n\treturn g(a, h(1), \"x\");\n}")
+
+	calls := []string{}
+	analysis.Inspect(file, func(node analysis.Node) bool {
+		if call, ok := node.(*analysis.CallExpr); ok {
+			calls = append(calls, call.Fun.(*analysis.Ident).Name)
+		}
+		return true
+	})
+	if fmt.Sprint(calls) != "[g h]" {
+		t.Fatalf("Expected: [g h], Actual: %v", calls)
+	}
+}
+
+func TestInspectSourceOrder(t *testing.T) {
+	text := "// a\nlong a;\n// b\nvoid function b() {\n\tlong x;\n\t// database query\n\tlong y;\n\t// done\n}\n// end\n"
+	file, _ := analysis.Parse("test.sct", text)
+
+	order := []string{}
+	analysis.Inspect(file, func(node analysis.Node) bool {
+		switch n := node.(type) {
+		case *analysis.Comment:
+			order = append(order, n.Text)
+		case *analysis.VarDecl:
+			order = append(order, n.Name.Name)
+		case *analysis.FuncDecl:
+			order = append(order, n.Name.Name)
+		}
+		return true
+	})
+	expected := "// a,a,// b,b,x,// database query,y,// done,// end"
+	if strings.Join(order, ",") != expected {
+		t.Fatalf("Expected: %s, Actual: %v", expected, order)
+	}
+	// the comments in the body are children of the block
+	body := file.Decls[1].(*analysis.FuncDecl).Body
+	if parent := analysis.NewTree(file).Parent(file.Comments[2]); parent != body {
+		t.Fatalf("Expected: the body of b, Actual: %T", parent)
+	}
+}
@@ -0,0 +1,40 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+)
+
+// Severity matches lsp.DiagnosticSeverity.
+type Severity int
+
+const (
+	SEVERITY_ERROR Severity = 1
+	SEVERITY_WARNING Severity = 2
+	SEVERITY_INFORMATION Severity = 3
+	SEVERITY_HINT Severity = 4
+)
+
+// Tag matches lsp.DiagnosticTag.
+type Tag int
+
+const (
+	// TAG_UNNECESSARY makes editors grey out unused or unreachable code.
+	TAG_UNNECESSARY Tag = 1
+	TAG_DEPRECATED Tag = 2
+)
+
+// Problem is a finding of the analysis beyond syntax errors, reported to the client as a diagnostic.
+type Problem struct {
+	Range lsp.Range
+	Severity Severity
+	Message string
+	Tags []Tag
+	// Related points to other places that explain the problem, possibly in other files.
+	Related []RelatedInformation
+}
+
+type RelatedInformation struct {
+	URI string
+	Range lsp.Range
+	Message string
+}
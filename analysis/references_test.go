@@ -0,0 +1,84 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"path/filepath"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "long function Shared() {\n\tMsgBox(\"lib\", \"\");\n\treturn 1;\n}\n",
+		// other.sct has its own Init, which is not the one of main.sct
+		"other.sct": "void function Init() {\n}\nvoid function Start() {\n\tInit();\n}\n",
+	}, analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text, string title", ""))
+	lib, main := scriptURI(state, "lib.sct"), scriptURI(state, "main.sct")
+	text := "#include \"lib.sct\"\n" +
+		"void function main() {\n" +
+		"\tlong n = Shared();\n" +
+		"\tn = n + Shared();\n" +
+		"\tMsgBox(\"main\", \"\");\n" +
+		"}\n" +
+		"void function Init() {\n" +
+		"\tInit();\n" +
+		"}\n"
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+
+	references := func(line, character int, includeDeclaration bool) []lsp.Location {
+		return state.References(logger, 1, lsp.ReferenceParams{
+			TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: main},
+				Position: lsp.Position{Line: line, Character: character},
+			},
+			Context: lsp.ReferenceContext{IncludeDeclaration: includeDeclaration},
+		}).Result
+	}
+	expect := func(locations []lsp.Location, expected ...lsp.Location) {
+		t.Helper()
+		if len(locations) != len(expected) {
+			t.Fatalf("Expected: %v, Actual: %v", expected, locations)
+		}
+		for i := range expected {
+			if locations[i].URI != expected[i].URI || locations[i].Range.Start != expected[i].Range.Start {
+				t.Fatalf("Expected: %v, Actual: %v", expected[i], locations[i])
+			}
+		}
+	}
+	at := func(uri string, line, character int) lsp.Location {
+		return lsp.Location{URI: uri, Range: analysis.LineRange(line, character, character)}
+	}
+
+	expect(references(2, 7, false), at(main, 3, 1), at(main, 3, 5))
+	expect(references(3, 1, true), at(main, 2, 6), at(main, 3, 1), at(main, 3, 5))
+	expect(references(2, 11, false), at(main, 2, 10), at(main, 3, 9))
+	expect(references(3, 10, true), at(lib, 0, 14), at(main, 2, 10), at(main, 3, 9))
+
+	expect(references(6, 15, true), at(main, 6, 14), at(main, 7, 1))
+
+	msgBox := references(4, 2, false)
+	expect(msgBox, at(lib, 1, 1), at(main, 4, 1))
+	if declared := references(4, 2, true); len(declared) != 3 || filepath.Base(analysis.URIToPath(declared[0].URI)) != "catalog.sct" {
+		t.Fatalf("Expected: the catalog declaration first, Actual: %v", declared)
+	}
+}
+
+func TestReferencesWithoutInclude(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "long function Shared() {\n\treturn 1;\n}\n",
+	})
+	lib, main := scriptURI(state, "lib.sct"), scriptURI(state, "main.sct")
+	// main.sct does not include lib.sct, the definition of Shared is found in the workspace anyway
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: "void function main() {\n\tShared();\n}\n"})
+
+	locations := state.References(logger, 1, lsp.ReferenceParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: main},
+			Position: lsp.Position{Line: 1, Character: 2},
+		},
+		Context: lsp.ReferenceContext{IncludeDeclaration: true},
+	}).Result
+	if len(locations) != 2 || locations[0].URI != lib || locations[1].URI != main || locations[1].Range.Start != (lsp.Position{Line: 1, Character: 1}) {
+		t.Fatalf("Expected: Shared in lib.sct and its call in main.sct, Actual: %v", locations)
+	}
+}
@@ -0,0 +1,269 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// renameTarget is what a rename changes. A local or parameter is renamed in its file,
+// functions and globals in every file of the workspace.
+type renameTarget struct {
+	document *Document
+	ident *Ident
+	// symbol is nil for a name declared in another file.
+	symbol *Symbol
+	local bool
+	// declarations are the functions or globals a non local target refers to.
+	declarations []Declaration
+}
+
+// renameTarget finds what the name under the cursor refers to and whether it can be renamed.
+func (s *State) renameTarget(document *Document, pos lsp.Position) (renameTarget, error) {
+	node, _ := document.Tree.NodeAt(pos)
+	ident, ok := node.(*Ident)
+	if !ok || ident.Name == "" {
+		return renameTarget{}, fmt.Errorf("only functions, parameters and variables can be renamed")
+	}
+	target := renameTarget{document: document, ident: ident, declarations: s.declarations(document, ident)}
+	symbol, ok := document.Resolution.Symbols[ident]
+	switch {
+	case ok && symbol.Kind == SYMBOL_BUILTIN:
+		return target, fmt.Errorf("'%s' is a function of the BORM catalog and cannot be renamed", ident.Name)
+	case ok && symbol.Kind == SYMBOL_TYPE:
+		return target, fmt.Errorf("'%s' is a builtin type and cannot be renamed", ident.Name)
+	case ok:
+		target.symbol = symbol
+		target.local = symbol.Kind == SYMBOL_LOCAL || symbol.Kind == SYMBOL_PARAM
+	case !isUnresolved(document.Resolution, ident):
+		return target, fmt.Errorf("only functions, parameters and variables can be renamed")
+	case len(target.declarations) == 0:
+		return target, fmt.Errorf("'%s' is not declared in the workspace", ident.Name)
+	}
+	return target, nil
+}
+
+// checkName refuses names that are no identifiers or already taken where the target is visible.
+func (s *State) checkName(target renameTarget, name string) error {
+	switch {
+	case !isIdentifier(name):
+		return fmt.Errorf("'%s' is not a valid name", name)
+	case IsKeyword(name):
+		return fmt.Errorf("'%s' is a keyword", name)
+	case IsBuiltinType(name):
+		return fmt.Errorf("'%s' is a builtin type", name)
+	}
+	if _, ok := s.Catalog.Lookup(name); ok {
+		return fmt.Errorf("'%s' is a function of the BORM catalog", name)
+	}
+	if target.local {
+		return checkLocalName(target, name)
+	}
+
+	for _, uri := range s.related(target) {
+		if _, ok := s.Workspace.Files[uri].Resolution.Root.Symbols[name]; ok {
+			return fmt.Errorf("'%s' is already declared in %s", name, filepath.Base(URIToPath(uri)))
+		}
+	}
+	// a use in a file not including the declaration would find any declaration of that name
+	for _, reference := range s.targetReferences(target) {
+		if s.includesDeclaration(target, reference.URI) {
+			continue
+		}
+		for _, declaration := range s.Workspace.Declarations(name) {
+			return fmt.Errorf("'%s' is already declared in %s", name, filepath.Base(URIToPath(declaration.URI)))
+		}
+	}
+	// a local of that name would hide the renamed function or global from its uses
+	for _, reference := range s.targetReferences(target) {
+		file := s.Workspace.Files[reference.URI]
+		scope := file.Resolution.ScopeAt(file.Tree.Path(reference.Ident.Pos()))
+		if symbol := scope.LookupAt(name, reference.Ident.Pos()); symbol != nil {
+			return fmt.Errorf("'%s' is already declared as %s in %s", name, symbol.Kind, filepath.Base(URIToPath(reference.URI)))
+		}
+	}
+	return nil
+}
+
+func checkLocalName(target renameTarget, name string) error {
+	resolution := target.document.Resolution
+	tree := target.document.Tree
+	scope := scopeOf(resolution.Root, target.symbol)
+	if scope == nil {
+		return nil
+	}
+	if _, ok := scope.Symbols[name]; ok {
+		return fmt.Errorf("'%s' is already declared in this scope", name)
+	}
+	// a nested declaration of that name would capture uses of the renamed variable
+	for _, use := range target.symbol.Uses {
+		if symbol := resolution.ScopeAt(tree.Path(use.Pos())).LookupAt(name, use.Pos()); symbol != nil && symbol.Kind != SYMBOL_FUNCTION && symbol.Kind != SYMBOL_GLOBAL {
+			return fmt.Errorf("'%s' is already declared as %s at line %d", name, symbol.Kind, symbol.Ident.Pos().Line+1)
+		}
+	}
+	// uses of an outer declaration of that name would refer to the renamed variable
+	captured := func(ident *Ident) bool {
+		return ident.Name == name && Contains(scope.Node, ident.Pos()) && PositionLess(target.symbol.Ident.Pos(), ident.Pos())
+	}
+	for ident, symbol := range resolution.Symbols {
+		if captured(ident) && symbol.Ident != ident && !within(scopeOf(resolution.Root, symbol), scope) {
+			return fmt.Errorf("'%s' is used at line %d and would refer to the renamed variable", name, ident.Pos().Line+1)
+		}
+	}
+	for _, ident := range resolution.Unresolved {
+		if captured(ident) {
+			return fmt.Errorf("'%s' is used at line %d and would refer to the renamed variable", name, ident.Pos().Line+1)
+		}
+	}
+	return nil
+}
+
+// scopeOf finds the scope declaring a symbol, nil for builtins.
+func scopeOf(scope *Scope, symbol *Symbol) *Scope {
+	if scope.Symbols[symbol.Name] == symbol {
+		return scope
+	}
+	for _, child := range scope.Children {
+		if found := scopeOf(child, symbol); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// within reports whether scope is outer or nested in it.
+func within(scope, outer *Scope) bool {
+	for ; scope != nil; scope = scope.Parent {
+		if scope == outer {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentifier(name string) bool {
+	if name == "" || !isIdentStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isIdentPart(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// renameEdits replaces the declarations and uses of the target with name, grouped by file.
+func (s *State) renameEdits(target renameTarget, name string) map[string][]lsp.TextEdit {
+	changes := map[string][]lsp.TextEdit{}
+	add := func(uri string, ident *Ident) {
+		changes[uri] = append(changes[uri], lsp.TextEdit{Range: s.location(uri, ident).Range, NewText: name})
+	}
+	if target.local {
+		uri := target.document.URI
+		add(uri, target.symbol.Ident)
+		for _, use := range target.symbol.Uses {
+			add(uri, use)
+		}
+		return changes
+	}
+	for _, declaration := range target.declarations {
+		add(declaration.URI, declaration.Symbol.Ident)
+	}
+	for _, reference := range s.targetReferences(target) {
+		add(reference.URI, reference.Ident)
+	}
+	return changes
+}
+
+// targetReferences are the uses of a function or global target, each once.
+func (s *State) targetReferences(target renameTarget) []Reference {
+	references := []Reference{}
+	seen := map[*Ident]bool{}
+	for _, declaration := range target.declarations {
+		for _, reference := range s.Workspace.References(declaration) {
+			if !seen[reference.Ident] {
+				seen[reference.Ident] = true
+				references = append(references, reference)
+			}
+		}
+	}
+	return references
+}
+
+// includesDeclaration reports whether a file declares the target or includes a file declaring it.
+func (s *State) includesDeclaration(target renameTarget, uri string) bool {
+	files := append([]string{uri}, s.Workspace.IncludedFiles(uri)...)
+	for _, declaration := range target.declarations {
+		for _, file := range files {
+			if file == declaration.URI {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// related are the files where the new name of a function or global could clash: the files
+// declaring or using it and the files these include.
+func (s *State) related(target renameTarget) []string {
+	uris := []string{}
+	seen := map[string]bool{}
+	add := func(uri string) {
+		for _, file := range append([]string{uri}, s.Workspace.IncludedFiles(uri)...) {
+			if _, ok := s.Workspace.Files[file]; ok && !seen[file] {
+				seen[file] = true
+				uris = append(uris, file)
+			}
+		}
+	}
+	for _, declaration := range target.declarations {
+		add(declaration.URI)
+	}
+	for _, reference := range s.targetReferences(target) {
+		add(reference.URI)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// PrepareRename tells the client the range of the name to rename, or why it cannot be renamed.
+func (s *State) PrepareRename(id int, uri string, position lsp.Position) (lsp.PrepareRenameResponse, error) {
+	response := lsp.PrepareRenameResponse{Response: lsp.Response{RPC: "2.0", Id: &id}}
+	document, ok := s.Documents[NormalizeURI(uri)]
+	if !ok {
+		return response, fmt.Errorf("document is not open")
+	}
+	target, err := s.renameTarget(document, document.FromClient(position))
+	if err != nil {
+		return response, err
+	}
+	response.Result = &lsp.PrepareRenameResult{
+		Range: document.RangeToClient(NodeRange(target.ident)),
+		Placeholder: target.ident.Name,
+	}
+	return response, nil
+}
+
+// Rename changes the name under the cursor in every place referring to the same declaration.
+func (s *State) Rename(id int, params lsp.RenameParams) (lsp.RenameResponse, error) {
+	response := lsp.RenameResponse{Response: lsp.Response{RPC: "2.0", Id: &id}}
+	document, ok := s.Documents[NormalizeURI(params.TextDocument.URI)]
+	if !ok {
+		return response, fmt.Errorf("document is not open")
+	}
+	target, err := s.renameTarget(document, document.FromClient(params.Position))
+	if err != nil {
+		return response, err
+	}
+	if params.NewName == target.ident.Name {
+		response.Result = &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{}}
+		return response, nil
+	}
+	if err := s.checkName(target, params.NewName); err != nil {
+		return response, err
+	}
+	response.Result = &lsp.WorkspaceEdit{Changes: s.renameEdits(target, params.NewName)}
+	return response, nil
+}
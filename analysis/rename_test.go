@@ -0,0 +1,129 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "long function Shared(long value) {\n\treturn value;\n}\n",
+		"other.sct": "void function Init() {\n}\nvoid function Start() {\n\tInit();\n}\n",
+	}, analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text, string title", ""))
+	lib, other, main := scriptURI(state, "lib.sct"), scriptURI(state, "other.sct"), scriptURI(state, "main.sct")
+	text := "#include \"lib.sct\"\n" +
+		"long total;\n" +
+		"void function main() {\n" +
+		"\tlong n = Shared(1);\n" +
+		"\tif (n > 0) {\n" +
+		"\t\tlong m = n;\n" +
+		"\t}\n" +
+		"\ttotal = n + Shared(2);\n" +
+		"\tMsgBox(\"\", \"\");\n" +
+		"}\n" +
+		"void function Init() {\n" +
+		"\tInit();\n" +
+		"}\n"
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+
+	rename := func(line, character int, name string) (map[string][]lsp.TextEdit, error) {
+		response, err := state.Rename(1, lsp.RenameParams{
+			TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: main},
+				Position: lsp.Position{Line: line, Character: character},
+			},
+			NewName: name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return response.Result.Changes, nil
+	}
+
+	prepared, err := state.PrepareRename(1, main, lsp.Position{Line: 3, Character: 11})
+	if err != nil || prepared.Result.Placeholder != "Shared" || prepared.Result.Range != analysis.LineRange(3, 10, 16) {
+		t.Fatalf("Expected: Shared at 3:10, Actual: %v %v", prepared.Result, err)
+	}
+	changes, err := rename(3, 11, "Common")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes[lib]) != 1 || len(changes[main]) != 2 || changes[main][1].Range != analysis.LineRange(7, 13, 19) || changes[main][1].NewText != "Common" {
+		t.Fatalf("Expected: Shared renamed in lib.sct and main.sct, Actual: %v", changes)
+	}
+
+	changes, err = rename(5, 11, "count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || len(changes[main]) != 4 {
+		t.Fatalf("Expected: n renamed 4 times in main.sct, Actual: %v", changes)
+	}
+
+	// other.sct declares an Init of its own
+	changes, err = rename(10, 15, "Setup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || len(changes[main]) != 2 || len(changes[other]) != 0 {
+		t.Fatalf("Expected: Init renamed in main.sct only, Actual: %v", changes)
+	}
+	if _, err := rename(10, 15, "Start"); err != nil {
+		t.Fatalf("Expected: Start of other.sct does not clash, Actual: %v", err)
+	}
+
+	refused := []struct {
+		line, character int
+		name string
+		message string
+	}{
+		{8, 2, "Box", "'MsgBox' is a function of the BORM catalog and cannot be renamed"},
+		{2, 1, "x", "only functions, parameters and variables can be renamed"},
+		{3, 6, "while", "'while' is a keyword"},
+		{3, 6, "2n", "'2n' is not a valid name"},
+		{3, 6, "MsgBox", "'MsgBox' is a function of the BORM catalog"},
+		{3, 6, "m", "'m' is already declared as local variable at line 6"},
+		{3, 6, "total", "'total' is used at line 8 and would refer to the renamed variable"},
+		{1, 6, "Shared", "'Shared' is already declared in lib.sct"},
+	}
+	for _, r := range refused {
+		if _, err := rename(r.line, r.character, r.name); err == nil || err.Error() != r.message {
+			t.Fatalf("Expected: %s, Actual: %v", r.message, err)
+		}
+	}
+}
+
+func TestRenameWithoutInclude(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "long function Shared() {\n\treturn 1;\n}\n",
+		"third.sct": "void function Taken() {\n}\n",
+	})
+	lib, main := scriptURI(state, "lib.sct"), scriptURI(state, "main.sct")
+	// main.sct calls Shared without including lib.sct
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: "void function main() {\n\tShared();\n}\n"})
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: lib, Version: 1, Text: "long function Shared() {\n\treturn 1;\n}\n"})
+
+	rename := func(name string) (lsp.RenameResponse, error) {
+		return state.Rename(1, lsp.RenameParams{
+			TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: lib},
+				Position: lsp.Position{Line: 0, Character: 15},
+			},
+			NewName: name,
+		})
+	}
+
+	response, err := rename("Other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := response.Result.Changes
+	if len(changes[lib]) != 1 || len(changes[main]) != 1 || changes[main][0].Range != analysis.LineRange(1, 1, 7) {
+		t.Fatalf("Expected: Shared renamed in lib.sct and its call in main.sct, Actual: %v", changes)
+	}
+	// the call would find Taken of third.sct
+	if _, err := rename("Taken"); err == nil || err.Error() != "'Taken' is already declared in third.sct" {
+		t.Fatalf("Expected: 'Taken' is already declared in third.sct, Actual: %v", err)
+	}
+}
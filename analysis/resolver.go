@@ -0,0 +1,330 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"fmt"
+)
+
+type SymbolKind int
+
+const (
+	SYMBOL_FUNCTION SymbolKind = iota
+	SYMBOL_GLOBAL
+	SYMBOL_PARAM
+	SYMBOL_LOCAL
+	// SYMBOL_BUILTIN is a function or constant of the catalog.
+	SYMBOL_BUILTIN
+	// SYMBOL_TYPE is a builtin type used as a conversion, like `string(4)`.
+	SYMBOL_TYPE
+)
+
+func (k SymbolKind) String() string {
+	switch k {
+	case SYMBOL_FUNCTION:
+		return "function"
+	case SYMBOL_GLOBAL:
+		return "global variable"
+	case SYMBOL_PARAM:
+		return "parameter"
+	case SYMBOL_LOCAL:
+		return "local variable"
+	case SYMBOL_BUILTIN:
+		return "builtin"
+	case SYMBOL_TYPE:
+		return "type"
+	}
+	return "unknown"
+}
+
+// builtinTypes are the types of the language itself, class types come from the catalog.
+var builtinTypes = map[string]bool{
+	"void": true,
+	"bool": true,
+	"short": true,
+	"int": true,
+	"long": true,
+	"double": true,
+	"string": true,
+	"variant": true,
+	"list": true,
+	"arraylist": true,
+	"map": true,
+}
+
+func IsBuiltinType(name string) bool {
+	return builtinTypes[name]
+}
+
+// Symbol is something a name can refer to.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	// Decl is the *FuncDecl, *VarDecl or *Param declaring the symbol, nil for builtins and types.
+	Decl Node
+	// Ident is the name in the declaration, nil for builtins and types.
+	Ident *Ident
+	// Type is the declared type, the return type for functions.
+	Type *TypeExpr
+	// Builtin is the catalog entry of SYMBOL_BUILTIN.
+	Builtin *BormFunction
+	// Uses are the identifiers referring to the symbol, the declaring one excluded.
+	Uses []*Ident
+}
+
+// Scope is the file, a function or a block. Function parameters and the
+// top level statements of the function body share the scope of the function.
+type Scope struct {
+	Parent *Scope
+	Node Node
+	Symbols map[string]*Symbol
+	Children []*Scope
+}
+
+func newScope(parent *Scope, node Node) *Scope {
+	scope := &Scope{Parent: parent, Node: node, Symbols: map[string]*Symbol{}}
+	if parent != nil {
+		parent.Children = append(parent.Children, scope)
+	}
+	return scope
+}
+
+// Lookup finds the symbol a name refers to in this scope or the ones enclosing it.
+func (s *Scope) Lookup(name string) *Symbol {
+	for scope := s; scope != nil; scope = scope.Parent {
+		if symbol, ok := scope.Symbols[name]; ok {
+			return symbol
+		}
+	}
+	return nil
+}
+
+// LookupAt is Lookup for a position within the scope. Locals only count once they are declared.
+func (s *Scope) LookupAt(name string, pos lsp.Position) *Symbol {
+	for scope := s; scope != nil; scope = scope.Parent {
+		symbol, ok := scope.Symbols[name]
+		if ok && (symbol.Kind != SYMBOL_LOCAL || PositionLess(symbol.Ident.Pos(), pos)) {
+			return symbol
+		}
+	}
+	return nil
+}
+
+// Resolution binds the names of one file.
+type Resolution struct {
+	Root *Scope
+	// Symbols maps every identifier the resolver could bind, declarations included, to its symbol.
+	Symbols map[*Ident]*Symbol
+	// Unresolved are the identifiers referring to nothing known.
+	Unresolved []*Ident
+	// Redeclared are declarations of a name already declared in the same scope.
+	Redeclared []*Ident
+	scopes map[Node]*Scope
+	builtins map[string]*Symbol
+}
+
+// Scope returns the scope a file, function, block or for statement opens, nil for other nodes.
+func (r *Resolution) Scope(node Node) *Scope {
+	return r.scopes[node]
+}
+
+// Problems reports the redeclarations, the first declaration of a name is the one in scope.
+func (r *Resolution) Problems() []Problem {
+	problems := []Problem{}
+	for _, ident := range r.Redeclared {
+		problems = append(problems, Problem{
+			Range: NodeRange(ident),
+			Severity: SEVERITY_ERROR,
+			Message: fmt.Sprintf("'%s' is already declared in this scope", ident.Name),
+		})
+	}
+	return problems
+}
+
+// ScopeAt returns the innermost scope containing the node at the end of path, see Tree.Path.
+func (r *Resolution) ScopeAt(path []Node) *Scope {
+	for i := len(path) - 1; i >= 0; i-- {
+		if scope, ok := r.scopes[path[i]]; ok {
+			return scope
+		}
+	}
+	return r.Root
+}
+
+// Resolve builds the scopes of a file and binds every identifier to its declaration.
+// Functions and global variables are visible in the whole file, locals from their declaration on.
+// Names no declaration matches are looked up in the catalog.
+func Resolve(file *File, catalog *Catalog) *Resolution {
+	r := &resolver{
+		res: &Resolution{
+			Symbols: map[*Ident]*Symbol{},
+			scopes: map[Node]*Scope{},
+			builtins: map[string]*Symbol{},
+		},
+		catalog: catalog,
+	}
+	r.openScope(file)
+	r.res.Root = r.scope
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *FuncDecl:
+			r.declare(d.Name, SYMBOL_FUNCTION, d, d.Type)
+		case *VarDecl:
+			r.declare(d.Name, SYMBOL_GLOBAL, d, d.Type)
+		}
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *FuncDecl:
+			r.function(d)
+		case *VarDecl:
+			r.expr(d.Value)
+		}
+	}
+	return r.res
+}
+
+type resolver struct {
+	res *Resolution
+	scope *Scope
+	catalog *Catalog
+}
+
+func (r *resolver) openScope(node Node) {
+	r.scope = newScope(r.scope, node)
+	r.res.scopes[node] = r.scope
+}
+
+func (r *resolver) closeScope() {
+	r.scope = r.scope.Parent
+}
+
+func (r *resolver) declare(ident *Ident, kind SymbolKind, decl Node, typ *TypeExpr) {
+	if ident.Name == "" {
+		return
+	}
+	symbol := &Symbol{Name: ident.Name, Kind: kind, Decl: decl, Ident: ident, Type: typ}
+	r.res.Symbols[ident] = symbol
+	if _, ok := r.scope.Symbols[ident.Name]; ok {
+		r.res.Redeclared = append(r.res.Redeclared, ident)
+		return
+	}
+	r.scope.Symbols[ident.Name] = symbol
+}
+
+func (r *resolver) use(ident *Ident) {
+	if ident.Name == "" {
+		return
+	}
+	symbol := r.scope.Lookup(ident.Name)
+	if symbol == nil {
+		symbol = r.builtin(ident.Name)
+	}
+	if symbol == nil {
+		r.res.Unresolved = append(r.res.Unresolved, ident)
+		return
+	}
+	symbol.Uses = append(symbol.Uses, ident)
+	r.res.Symbols[ident] = symbol
+}
+
+// builtin returns the symbol of a catalog entry or builtin type, one per name and file.
+func (r *resolver) builtin(name string) *Symbol {
+	if symbol, ok := r.res.builtins[name]; ok {
+		return symbol
+	}
+	var symbol *Symbol
+	if function, ok := r.catalog.Lookup(name); ok {
+		symbol = &Symbol{Name: name, Kind: SYMBOL_BUILTIN, Builtin: function}
+	} else if IsBuiltinType(name) {
+		symbol = &Symbol{Name: name, Kind: SYMBOL_TYPE}
+	} else {
+		return nil
+	}
+	r.res.builtins[name] = symbol
+	return symbol
+}
+
+func (r *resolver) function(function *FuncDecl) {
+	r.openScope(function)
+	defer r.closeScope()
+	for _, param := range function.Params {
+		r.declare(param.Name, SYMBOL_PARAM, param, param.Type)
+	}
+	if function.Body == nil {
+		return
+	}
+	r.res.scopes[function.Body] = r.scope
+	for _, stmt := range function.Body.List {
+		r.stmt(stmt)
+	}
+}
+
+func (r *resolver) block(block *BlockStmt) {
+	r.openScope(block)
+	defer r.closeScope()
+	for _, stmt := range block.List {
+		r.stmt(stmt)
+	}
+}
+
+func (r *resolver) stmt(stmt Stmt) {
+	switch s := stmt.(type) {
+	case nil:
+	case *BlockStmt:
+		r.block(s)
+	case *VarDecl:
+		// the variable is not visible in its own initializer
+		r.expr(s.Value)
+		r.declare(s.Name, SYMBOL_LOCAL, s, s.Type)
+	case *ExprStmt:
+		r.expr(s.X)
+	case *IfStmt:
+		r.expr(s.Cond)
+		r.stmt(s.Body)
+		r.stmt(s.Else)
+	case *WhileStmt:
+		r.expr(s.Cond)
+		r.stmt(s.Body)
+	case *ForStmt:
+		r.openScope(s)
+		r.stmt(s.Init)
+		r.expr(s.Cond)
+		r.stmt(s.Post)
+		r.stmt(s.Body)
+		r.closeScope()
+	case *ReturnStmt:
+		r.expr(s.Result)
+	}
+}
+
+func (r *resolver) expr(expr Expr) {
+	switch e := expr.(type) {
+	case nil:
+	case *Ident:
+		r.use(e)
+	case *CallExpr:
+		r.expr(e.Fun)
+		for _, arg := range e.Args {
+			r.expr(arg)
+		}
+	case *CallbackExpr:
+		r.use(e.Name)
+	case *ParenExpr:
+		r.expr(e.X)
+	case *UnaryExpr:
+		r.expr(e.X)
+	case *BinaryExpr:
+		r.expr(e.X)
+		r.expr(e.Y)
+	case *AssignExpr:
+		r.expr(e.Target)
+		r.expr(e.Value)
+	case *IndexExpr:
+		r.expr(e.X)
+		r.expr(e.Index)
+	case *MemberExpr:
+		// members belong to the type of X, which only the type checker knows
+		r.expr(e.X)
+	}
+}
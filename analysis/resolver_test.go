@@ -0,0 +1,125 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"testing"
+)
+
+const resolverScript = `long total = 0;
+
+long function Add(long value) {
+	long result = total + value;
+	for (long i = 0; i < value; i++) {
+		long result = i;
+		total += result;
+	}
+	if (value > 0) {
+		string s = string(value);
+		MsgBox(s, missing);
+	}
+	return result;
+}
+
+void function main() {
+	Add(Later());
+	BGSleep(callback main);
+}
+
+long function Later() {
+	return 1;
+}
+`
+
+func resolveScript(t *testing.T) (*analysis.File, *analysis.Resolution) {
+	file, errors := analysis.Parse("file:///test.sct", resolverScript)
+	if len(errors) != 0 {
+		t.Fatalf("Expected: no errors, Actual: %v", errors)
+	}
+	catalog := analysis.NewCatalog([]analysis.BormFunction{
+		analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text, string title", ""),
+		analysis.NewBormFunction("Global", "Programm", "void", "BGSleep", "long milliseconds", ""),
+	})
+	return file, analysis.Resolve(file, catalog)
+}
+
+func TestResolveBindings(t *testing.T) {
+	file, resolution := resolveScript(t)
+
+	uses := map[string][]analysis.SymbolKind{}
+	expected := map[string][]analysis.SymbolKind{
+		"total": {analysis.SYMBOL_GLOBAL, analysis.SYMBOL_GLOBAL},
+		"value": {analysis.SYMBOL_PARAM, analysis.SYMBOL_PARAM, analysis.SYMBOL_PARAM, analysis.SYMBOL_PARAM},
+		"result": {analysis.SYMBOL_LOCAL, analysis.SYMBOL_LOCAL},
+		"i": {analysis.SYMBOL_LOCAL, analysis.SYMBOL_LOCAL, analysis.SYMBOL_LOCAL},
+		"string": {analysis.SYMBOL_TYPE},
+		"s": {analysis.SYMBOL_LOCAL},
+		"MsgBox": {analysis.SYMBOL_BUILTIN},
+		"Add": {analysis.SYMBOL_FUNCTION},
+		"Later": {analysis.SYMBOL_FUNCTION},
+		"BGSleep": {analysis.SYMBOL_BUILTIN},
+		"main": {analysis.SYMBOL_FUNCTION},
+	}
+	analysis.Inspect(file, func(node analysis.Node) bool {
+		ident, ok := node.(*analysis.Ident)
+		if !ok {
+			return true
+		}
+		if symbol, ok := resolution.Symbols[ident]; ok && symbol.Ident != ident {
+			uses[ident.Name] = append(uses[ident.Name], symbol.Kind)
+		}
+		return true
+	})
+	for name, kinds := range expected {
+		if len(uses[name]) != len(kinds) {
+			t.Fatalf("Expected: %d uses of %s, Actual: %d", len(kinds), name, len(uses[name]))
+		}
+		for i, kind := range kinds {
+			if uses[name][i] != kind {
+				t.Fatalf("Expected: %s %s, Actual: %s", name, kind, uses[name][i])
+			}
+		}
+	}
+
+	if len(resolution.Unresolved) != 1 || resolution.Unresolved[0].Name != "missing" {
+		t.Fatalf("Expected: missing unresolved, Actual: %v", resolution.Unresolved)
+	}
+}
+
+func TestResolveShadowing(t *testing.T) {
+	file, resolution := resolveScript(t)
+	add := file.Decls[1].(*analysis.FuncDecl)
+	outer := add.Body.List[0].(*analysis.VarDecl)
+	loop := add.Body.List[1].(*analysis.ForStmt)
+	inner := loop.Body.(*analysis.BlockStmt).List[0].(*analysis.VarDecl)
+
+	// total += result in the loop binds to the inner result, return result to the outer one
+	assign := loop.Body.(*analysis.BlockStmt).List[1].(*analysis.ExprStmt).X.(*analysis.AssignExpr)
+	if resolution.Symbols[assign.Value.(*analysis.Ident)].Decl != inner {
+		t.Fatalf("Expected: inner result")
+	}
+	ret := add.Body.List[3].(*analysis.ReturnStmt)
+	if resolution.Symbols[ret.Result.(*analysis.Ident)].Decl != outer {
+		t.Fatalf("Expected: outer result")
+	}
+	if resolution.Scope(add.Body) != resolution.Scope(add) || resolution.Scope(loop).Parent != resolution.Scope(add) {
+		t.Fatalf("Expected: loop scope nested in function scope")
+	}
+	if len(resolution.Redeclared) != 0 {
+		t.Fatalf("Expected: no redeclarations, Actual: %d", len(resolution.Redeclared))
+	}
+}
+
+func TestResolveRedeclared(t *testing.T) {
+	file, errors := analysis.Parse("file:///test.sct", "long count;\nstring count;\n\nvoid function main(long n) {\n\tlong n = 1;\n\tfor (long i = 0; i < n; i++) {\n\t\tlong n = i;\n\t}\n}\n")
+	if len(errors) != 0 {
+		t.Fatalf("Expected: no errors, Actual: %v", errors)
+	}
+	problems := analysis.Resolve(file, analysis.NewCatalog(nil)).Problems()
+	// the local of the loop only shadows the parameter
+	if len(problems) != 2 || problems[0].Range.Start.Line != 1 || problems[1].Range.Start.Line != 4 {
+		t.Fatalf("Expected: count in line 1 and n in line 4, Actual: %v", problems)
+	}
+	if problems[0].Message != "'count' is already declared in this scope" {
+		t.Fatalf("Expected: 'count' is already declared in this scope, Actual: %s", problems[0].Message)
+	}
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"strings"
+)
+
+// CallAt finds the innermost call whose argument list contains offset. It works on the tokens
+// so it also finds calls the parser cannot read yet, while the arguments are being typed.
+// It returns the name of the called function and the index of the argument at offset.
+func CallAt(text string, offset int) (Token, int, bool) {
+	tokens := []Token{}
+	for _, token := range Tokenize(text[:min(max(offset, 0), len(text))]) {
+		if token.Kind != TOKEN_COMMENT {
+			tokens = append(tokens, token)
+		}
+	}
+	depth := 0
+	argument := 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].Kind != TOKEN_PUNCT {
+			continue
+		}
+		switch tokens[i].Value {
+		case ")", "]":
+			depth++
+		case "[":
+			if depth > 0 {
+				depth--
+			} else {
+				// the commas seen so far are inside an open index
+				argument = 0
+			}
+		case "(":
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if i > 0 && tokens[i-1].Kind == TOKEN_IDENT {
+				if i > 1 && tokens[i-2].Kind == TOKEN_PUNCT && tokens[i-2].Value == "." {
+					return Token{}, 0, false
+				}
+				return tokens[i-1], argument, true
+			}
+			if i > 0 && tokens[i-1].Kind == TOKEN_KEYWORD {
+				return Token{}, 0, false
+			}
+			// a parenthesized argument, the commas seen so far were inside it
+			argument = 0
+		case ",":
+			if depth == 0 {
+				argument++
+			}
+		case "{", "}", ";":
+			if depth == 0 {
+				return Token{}, 0, false
+			}
+		}
+	}
+	return Token{}, 0, false
+}
+
+// signature finds the function a call names: a function of the file or the workspace, or a
+// catalog entry. Functions declared in a script are documented by the comment above them.
+func (s *State) signature(document *Document, name Token) (Signature, string, bool) {
+	scope := document.Resolution.ScopeAt(document.Tree.Path(name.Start))
+	if symbol := scope.LookupAt(name.Value, name.Start); symbol != nil {
+		switch symbol.Kind {
+		case SYMBOL_FUNCTION:
+			function := symbol.Decl.(*FuncDecl)
+			return FuncSignature(function), docComment(document.Tree.File, function), true
+		case SYMBOL_BUILTIN:
+			return BuiltinSignature(symbol.Builtin), symbol.Builtin.Description, true
+		default:
+			return Signature{}, "", false
+		}
+	}
+	if function, ok := s.Catalog.Lookup(name.Value); ok {
+		return BuiltinSignature(function), function.Description, true
+	}
+	for _, declaration := range s.Workspace.Declarations(name.Value) {
+		if function, ok := declaration.Symbol.Decl.(*FuncDecl); ok {
+			return FuncSignature(function), docComment(s.Workspace.Files[declaration.URI].Tree.File, function), true
+		}
+	}
+	return Signature{}, "", false
+}
+
+// docComment is the text of the comments on the lines right above a function, without markers.
+func docComment(file *File, function *FuncDecl) string {
+	lines := []string{}
+	line := function.Pos().Line
+	for i := len(file.Comments) - 1; i >= 0; i-- {
+		comment := file.Comments[i]
+		if comment.Stop.Line >= line {
+			continue
+		}
+		if comment.Stop.Line != line-1 {
+			break
+		}
+		text := strings.TrimPrefix(comment.Text, "//")
+		if strings.HasPrefix(text, "/*") {
+			text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		}
+		lines = append([]string{strings.TrimSpace(text)}, lines...)
+		line = comment.Slash.Line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SignatureHelp shows the signature of the call around the cursor with the argument being typed.
+func (s *State) SignatureHelp(id int, uri string, position lsp.Position) lsp.SignatureHelpResponse {
+	response := lsp.SignatureHelpResponse{Response: lsp.Response{RPC: "2.0", Id: &id}}
+	document, ok := s.Documents[NormalizeURI(uri)]
+	if !ok {
+		return response
+	}
+	name, argument, ok := CallAt(document.Text, document.Offset(document.FromClient(position)))
+	if !ok {
+		return response
+	}
+	signature, documentation, ok := s.signature(document, name)
+	if !ok {
+		return response
+	}
+
+	label := signature.Name + "("
+	if signature.Result != "" {
+		label = signature.Result + " " + label
+	}
+	parameters := []lsp.ParameterInformation{}
+	for i, param := range signature.Params {
+		if i > 0 {
+			label += ", "
+		}
+		start := document.encoding().toUnits(label, len(label))
+		label += param.String()
+		parameters = append(parameters, lsp.ParameterInformation{Label: [2]int{start, document.encoding().toUnits(label, len(label))}})
+	}
+	label += ")"
+
+	response.Result = &lsp.SignatureHelp{
+		Signatures: []lsp.SignatureInformation{{
+			Label: label,
+			Documentation: strings.TrimSpace(documentation),
+			Parameters: parameters,
+		}},
+		ActiveParameter: argument,
+	}
+	return response
+}
@@ -0,0 +1,73 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestCallAt(t *testing.T) {
+	tests := []struct {
+		text string
+		name string
+		argument int
+	}{
+		{"Foo(", "Foo", 0},
+		{"Foo(a, ", "Foo", 1},
+		{"Foo(a, \"x, y\", b[1, ", "Foo", 2},
+		{"Foo(a, Bar(b, ", "Bar", 1},
+		{"Foo(a, Bar(b), ", "Foo", 2},
+		{"Foo(a, (b, ", "Foo", 1},
+		{"Foo(a, /* , */ ", "Foo", 1},
+		{"if (a, ", "", 0},
+		{"Foo(a);\nb = (", "", 0},
+		{"window.Show(", "", 0},
+	}
+	for _, test := range tests {
+		name, argument, ok := analysis.CallAt(test.text, len(test.text))
+		if ok != (test.name != "") || name.Value != test.name || argument != test.argument {
+			t.Fatalf("Expected: %s %d in %q, Actual: %s %d", test.name, test.argument, test.text, name.Value, argument)
+		}
+	}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib.sct": "// Adds two numbers.\nlong function Add(long a, long &b) {\n\treturn a + b;\n}\n",
+	}, analysis.NewBormFunction("Dialog", "Dialog", "long", "SetGridFrozenRows", "string _p_DialogKey, string _p_ElementName, long _p_RowCount, long _p_AtTop", "Friert die ersten Zeilen des Grids ein"))
+	main := scriptURI(state, "main.sct")
+	text := "#include \"lib.sct\"\n" +
+		"void function main() {\n" +
+		"\tlong n = 1;\n" +
+		"\tSetGridFrozenRows(\"dlg\", \"grid\", Add(1, n), \n" +
+		"}\n"
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+
+	help := func(line, character int) *lsp.SignatureHelp {
+		return state.SignatureHelp(1, main, lsp.Position{Line: line, Character: character}).Result
+	}
+
+	builtin := help(3, 35)
+	if builtin == nil || builtin.ActiveParameter != 2 || builtin.Signatures[0].Documentation != "Friert die ersten Zeilen des Grids ein" {
+		t.Fatalf("Expected: SetGridFrozenRows at its third parameter, Actual: %v", builtin)
+	}
+	signature := builtin.Signatures[0]
+	if signature.Label != "long SetGridFrozenRows(string _p_DialogKey, string _p_ElementName, long _p_RowCount, long _p_AtTop)" {
+		t.Fatalf("Expected: the signature of SetGridFrozenRows, Actual: %s", signature.Label)
+	}
+	if active := signature.Parameters[2].Label; signature.Label[active[0]:active[1]] != "long _p_RowCount" {
+		t.Fatalf("Expected: long _p_RowCount, Actual: %v", active)
+	}
+
+	add := help(3, 41)
+	if add == nil || add.ActiveParameter != 1 || add.Signatures[0].Label != "long Add(long a, long &b)" || add.Signatures[0].Documentation != "Adds two numbers." {
+		t.Fatalf("Expected: Add at its second parameter, Actual: %v", add)
+	}
+
+	if outer := help(3, 45); outer == nil || outer.ActiveParameter != 3 || outer.Signatures[0].Parameters[3].Label != [2]int{85, 98} {
+		t.Fatalf("Expected: SetGridFrozenRows at its last parameter, Actual: %v", outer)
+	}
+	if none := help(2, 10); none != nil {
+		t.Fatalf("Expected: no signature outside of a call, Actual: %v", none)
+	}
+}
@@ -4,59 +4,321 @@ import (
 	"borm-lsp/lsp"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 type State struct {
-	Documents map[string]SyntaxNode
+	Documents map[string]*Document
+	Catalog *Catalog
+	Workspace *Workspace
+	// Encoding is the position encoding agreed on with the client.
+	Encoding PositionEncoding
+	// DeclarationDir is where the declarations of the catalog are generated.
+	DeclarationDir string
+	// EntryPoints are the functions the runtime calls, they are never dead code.
+	EntryPoints map[string]bool
+	// WatchFiles is set when the client lets us register for changes to files on disk.
+	WatchFiles bool
 }
 
 func NewState() State {
 	return State{
-		Documents:map[string]SyntaxNode{}, 
+		Documents:map[string]*Document{}, 
+		Catalog: NewCatalog(nil),
+		Workspace: NewWorkspace(),
+		Encoding: ENCODING_UTF16,
+		DeclarationDir: filepath.Join(os.TempDir(), "bormlsp"),
+		EntryPoints: map[string]bool{ENTRY_POINT: true},
 	}
 }
 
-func getDiagnosticsForFile(tree SyntaxNode) []lsp.Diagnostic {
-	diagnostics := []lsp.Diagnostic{}
+// Initialize settles what the server and the client agreed on.
+func (s *State) Initialize(logger *log.Logger, params lsp.InitializeRequestParams) {
+	if params.Capabilities.General != nil {
+		s.Encoding = NegotiateEncoding(params.Capabilities.General.PositionEncodings)
+	}
+	s.Workspace.Encoding = s.Encoding
+	logger.Printf("Position encoding: %s", s.Encoding)
+	if workspace := params.Capabilities.Workspace; workspace != nil && workspace.DidChangeWatchedFiles != nil {
+		s.WatchFiles = workspace.DidChangeWatchedFiles.DynamicRegistration
+	}
+
+	for _, folder := range params.WorkspaceFolders {
+		if path := URIToPath(folder.URI); path != "" {
+			s.Workspace.Roots = append(s.Workspace.Roots, path)
+		}
+	}
+	if len(s.Workspace.Roots) == 0 && params.RootURI != nil {
+		if path := URIToPath(*params.RootURI); path != "" {
+			s.Workspace.Roots = append(s.Workspace.Roots, path)
+		}
+	}
+	if params.InitializationOptions != nil {
+		s.configure(logger, *params.InitializationOptions)
+	}
+	s.Workspace.Scan(logger, s.Catalog)
+}
 
-	for _, node := range tree.GetBadNodes() {
-		var msg string
-		switch node.Type {
-		case INCLUDE:
-			msg = "Bad include statement. Please reformat the statement: #include <headder-file> OR #include \"file_path\""
-		default:
-			msg = "Syntax error"
+// configure takes over the settings. Relative include paths are taken relative to the first workspace folder.
+func (s *State) configure(logger *log.Logger, settings lsp.Settings) {
+	config := IncludeConfig{BaseDirectory: settings.BaseDirectory, IncludePaths: []string{}}
+	for _, path := range settings.IncludePaths {
+		if !isAbsolute(path) && len(s.Workspace.Roots) > 0 {
+			path = filepath.Join(s.Workspace.Roots[0], path)
 		}
+		config.IncludePaths = append(config.IncludePaths, path)
+	}
+	logger.Printf("Base directory: %q, include paths: %v", config.BaseDirectory, config.IncludePaths)
+	s.EntryPoints = map[string]bool{ENTRY_POINT: true}
+	for _, name := range settings.Callbacks {
+		s.EntryPoints[name] = true
+	}
+	s.Workspace.Configure(logger, config, s.Catalog)
+}
+
+// ChangeConfiguration takes over new settings and diagnoses all open documents again.
+func (s *State) ChangeConfiguration(logger *log.Logger, settings *lsp.Settings) []lsp.DiagnosticParams {
+	if settings == nil {
+		return []lsp.DiagnosticParams{}
+	}
+	s.configure(logger, *settings)
+	all := []lsp.DiagnosticParams{}
+	for _, uri := range s.openURIs() {
+		s.analyze(logger, s.Documents[uri])
+		all = append(all, s.Diagnostics(uri))
+	}
+	return all
+}
+
+// openURIs returns the open documents in a stable order.
+func (s *State) openURIs() []string {
+	uris := []string{}
+	for uri := range s.Documents {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// LoadCatalog reads the function catalog, without it builtins are unknown.
+func (s *State) LoadCatalog(logger *log.Logger, filename string) {
+	functions, malformed, err := ReadFunctionsFromFile(filename)
+	if err != nil {
+		logger.Printf("Could not read catalog %s: %s", filename, err)
+		return
+	}
+	for _, entry := range malformed {
+		logger.Printf("Malformed catalog entry in %s %s", filename, entry)
+	}
+	s.Catalog = NewCatalog(functions)
+	logger.Printf("Read %d catalog entries from %s", len(functions), filename)
+}
+
+// analyze brings everything derived from the syntax tree of a document up to date.
+func (s *State) analyze(logger *log.Logger, document *Document) {
+	document.Resolution = Resolve(document.Tree.File, s.Catalog)
+	document.Types = Check(document.Tree.File, document.Resolution)
+	includes, problems := ResolveIncludes(document.Tree.File, s.Workspace.Config)
+	document.Includes = includes
+	document.Problems = append(append([]Problem{}, document.Types.Problems...), problems...)
+	document.Problems = append(document.Problems, document.Resolution.Problems()...)
+	document.Problems = append(document.Problems, CheckFlow(document.Tree.File, document.Resolution)...)
+	s.Workspace.Update(document)
+	s.Workspace.LoadIncludes(logger, includes, s.Catalog)
+}
+
+// workspaceProblems are the problems of a document that depend on other files.
+func (s *State) workspaceProblems(document *Document) []Problem {
+	problems := []Problem{}
+	if len(s.Catalog.Functions) == 0 {
+		// without the catalog every builtin would be reported
+		return problems
+	}
+	for _, ident := range document.Resolution.Unresolved {
+		if len(s.Workspace.Declarations(ident.Name)) == 0 {
+			problems = append(problems, Problem{
+				Range: NodeRange(ident),
+				Severity: SEVERITY_ERROR,
+				Message: fmt.Sprintf("'%s' is not declared", ident.Name),
+			})
+		}
+	}
+	return problems
+}
+
+func (s *State) getDiagnosticsForFile(document *Document, problems []Problem) []lsp.Diagnostic {
+	diagnostics := []lsp.Diagnostic{}
+
+	for _, err := range document.Errors {
 		diagnostics = append(diagnostics, lsp.Diagnostic{
-			Range: lsp.Range{Start: node.Start, End: node.End},
+			Range: document.RangeToClient(err.Range),
 			Severity: 1,
-			Source: "idk lol",
-			Message: msg,
+			Source: "bormlsp",
+			Message: err.Message,
 		})
 	}
+	for _, problem := range problems {
+		diagnostics = append(diagnostics, s.problemDiagnostic(document, problem))
+	}
 	
 	return diagnostics
 }
 
-func (s *State) OpenDocument(logger *log.Logger, uri, text string) []lsp.Diagnostic {
-	s.Documents[uri] = CreateTree(logger, uri, text)
-	return getDiagnosticsForFile(s.Documents[uri])
+func (s *State) problemDiagnostic(document *Document, problem Problem) lsp.Diagnostic {
+	var tags []int
+	for _, tag := range problem.Tags {
+		tags = append(tags, int(tag))
+	}
+	return lsp.Diagnostic{
+		Range: document.RangeToClient(problem.Range),
+		Severity: int(problem.Severity),
+		Source: "bormlsp",
+		Message: problem.Message,
+		Tags: tags,
+		RelatedInformation: s.relatedToClient(problem.Related),
+	}
+}
+
+// relatedToClient converts related information with the document of the file it points to.
+func (s *State) relatedToClient(related []RelatedInformation) []lsp.DiagnosticRelatedInformation {
+	if len(related) == 0 {
+		return nil
+	}
+	information := []lsp.DiagnosticRelatedInformation{}
+	for _, info := range related {
+		r := info.Range
+		if file, ok := s.Workspace.Files[info.URI]; ok {
+			r = file.Document.RangeToClient(r)
+		}
+		information = append(information, lsp.DiagnosticRelatedInformation{
+			Location: lsp.Location{URI: info.URI, Range: r},
+			Message: info.Message,
+		})
+	}
+	return information
+}
+
+// Diagnostics returns the diagnostics of an open document for the version they were computed for.
+func (s *State) Diagnostics(uri string) lsp.DiagnosticParams {
+	document, ok := s.Documents[uri]
+	if !ok {
+		return lsp.DiagnosticParams{URI: uri, Diagnostics: []lsp.Diagnostic{}}
+	}
+	problems := append(append([]Problem{}, document.Problems...), s.workspaceProblems(document)...)
+	problems = append(problems, s.Workspace.IncludeProblems(uri)...)
+	for _, missing := range s.Workspace.MissingIncludes(uri) {
+		problems = append(problems, missing.Problem())
+	}
+	for _, unused := range s.Workspace.UnusedIncludes(uri) {
+		problems = append(problems, UnusedIncludeProblem(unused))
+	}
+	for _, dead := range s.Workspace.DeadCode(uri, s.EntryPoints) {
+		problems = append(problems, dead.Problem())
+	}
+	version := document.Version
+	return lsp.DiagnosticParams{
+		URI: uri,
+		Version: &version,
+		Diagnostics: s.getDiagnosticsForFile(document, problems),
+	}
+}
+
+// diagnoseAll returns the diagnostics of every open document, uri first. A change to one
+// file can change the diagnostics of every other one, the documents including it among them.
+func (s *State) diagnoseAll(uri string) []lsp.DiagnosticParams {
+	all := []lsp.DiagnosticParams{s.Diagnostics(uri)}
+	for _, other := range s.openURIs() {
+		if other != uri {
+			all = append(all, s.Diagnostics(other))
+		}
+	}
+	return all
 }
 
-func (s *State) UpdateDocument(logger *log.Logger, uri, text string) []lsp.Diagnostic {
-	s.Documents[uri] = CreateTree(logger, uri, text)
-	return getDiagnosticsForFile(s.Documents[uri])
+func (s *State) OpenDocument(logger *log.Logger, item lsp.TextDocumentItem) []lsp.DiagnosticParams {
+	item.URI = NormalizeURI(item.URI)
+	document := NewDocument(item.URI, item.LanguageId, item.Version, item.Text)
+	document.Encoding = s.Encoding
+	s.Documents[item.URI] = document
+	s.analyze(logger, document)
+	return s.diagnoseAll(item.URI)
+}
+
+func (s *State) UpdateDocument(logger *log.Logger, identifier lsp.VersionedTextDocumentIdentifier, changes []lsp.TextDocumentChangeEvent) []lsp.DiagnosticParams {
+	identifier.URI = NormalizeURI(identifier.URI)
+	document, ok := s.Documents[identifier.URI]
+	if !ok {
+		logger.Printf("Change to %s which is not open", identifier.URI)
+		return []lsp.DiagnosticParams{}
+	}
+	if identifier.Version <= document.Version {
+		logger.Printf("Change to %s has version %d, document is at %d", identifier.URI, identifier.Version, document.Version)
+	}
+	document.Update(identifier.Version, changes)
+	s.analyze(logger, document)
+	return s.diagnoseAll(identifier.URI)
+}
+
+// CloseDocument drops the document, the diagnostics returned clear the ones published for it.
+func (s *State) CloseDocument(logger *log.Logger, uri string) []lsp.DiagnosticParams {
+	uri = NormalizeURI(uri)
+	delete(s.Documents, uri)
+	s.Workspace.Closed(logger, uri, s.Catalog)
+	return s.diagnoseAll(uri)
+}
+
+// WatchedFilesChanged brings the index up to date with the files on disk.
+func (s *State) WatchedFilesChanged(logger *log.Logger, changes []lsp.FileEvent) []lsp.DiagnosticParams {
+	moved := false
+	for _, change := range changes {
+		change.URI = NormalizeURI(change.URI)
+		path := URIToPath(change.URI)
+		if path == "" || !IsScript(path) {
+			continue
+		}
+		if change.Type == lsp.FILE_DELETED {
+			s.Workspace.Remove(change.URI)
+		} else {
+			s.Workspace.Load(logger, change.URI, s.Catalog)
+		}
+		moved = moved || change.Type != lsp.FILE_CHANGED
+	}
+	if moved {
+		// includes may find other files now
+		s.Workspace.Configure(logger, s.Workspace.Config, s.Catalog)
+	}
+	all := []lsp.DiagnosticParams{}
+	for _, uri := range s.openURIs() {
+		if moved {
+			s.analyze(logger, s.Documents[uri])
+		}
+		all = append(all, s.Diagnostics(uri))
+	}
+	return all
 }
 
 func (s *State) Hover(logger *log.Logger, id int, uri string, position lsp.Position) lsp.HoverResponse {
-	node, found := s.Documents[uri].FindNodeAtPosition(logger, position)
+	uri = NormalizeURI(uri)
 	var content string
-	if found {
-		content = fmt.Sprintf("This is a node of type %s! Value: %s", node.Type, node.Value)
+	document, ok := s.Documents[uri]
+	if !ok {
+		content = "Document is not open"
+	} else if node, found := document.Tree.NodeAt(document.FromClient(position)); found {
+		content = fmt.Sprintf("This is a node of type %s! %s", nodeTypeName(node), describeNode(node))
+		if ident, ok := node.(*Ident); ok {
+			content += describeSymbol(document.Resolution, ident)
+		}
+		if expr, ok := node.(Expr); ok && document.Types.Types[expr] != TYPE_UNKNOWN {
+			content += fmt.Sprintf(" of type %s", document.Types.Types[expr])
+		}
+		if function := document.Tree.EnclosingFunc(node); function != nil && function != node {
+			content += fmt.Sprintf(" (in function %s)", function.Name.Name)
+		}
 	} else {
-		content = fmt.Sprintf(
-			"Closest node type: %s Value: %s Line: %d Col: %d", 
-			node.Type, node.Value, node.Start.Line, node.Start.Character)
+		content = fmt.Sprintf("No node at Line: %d Col: %d", position.Line, position.Character)
 	}
 	return lsp.HoverResponse {
 		Response: lsp.Response {
@@ -69,31 +331,174 @@ func (s *State) Hover(logger *log.Logger, id int, uri string, position lsp.Posit
 	}
 }
 
-func (s *State) Definition(id int, uri string, position lsp.Position) lsp.DefinitionResponse {
-	//TODO: correct implementation for go-to-definition
+// Definition finds the declarations of the name or the file of the include under the cursor.
+// Names other files declare can have several, builtins go to the generated catalog declarations.
+func (s *State) Definition(logger *log.Logger, id int, uri string, position lsp.Position) lsp.DefinitionResponse {
+	locations := []lsp.Location{}
+	if document, ok := s.Documents[NormalizeURI(uri)]; ok {
+		locations = s.definitions(logger, document, document.FromClient(position))
+	}
 	return lsp.DefinitionResponse {
 		Response: lsp.Response {
 			RPC: "2.0",
 			Id: &id,
 		},
-		Result: lsp.Location {
-			URI: uri,
-			Range: lsp.Range{
-				Start: lsp.Position {
-					Line: position.Line - 1,
-					Character: 0,
-				},
-				End: lsp.Position {
-					Line: position.Line - 1,
-					Character: 0,
-				},
-			},
+		Result: locations,
+	}
+}
+
+func (s *State) definitions(logger *log.Logger, document *Document, pos lsp.Position) []lsp.Location {
+	locations := []lsp.Location{}
+	node, found := document.Tree.NodeAt(pos)
+	if !found {
+		return locations
+	}
+	if directive, ok := node.(*IncludeDirective); ok {
+		for _, include := range document.Includes {
+			if include.Directive == directive && include.URI != "" {
+				locations = append(locations, lsp.Location{URI: include.URI, Range: LineRange(0, 0, 0)})
+			}
+		}
+		return locations
+	}
+	ident, ok := node.(*Ident)
+	if !ok {
+		return locations
+	}
+
+	symbol, ok := document.Resolution.Symbols[ident]
+	if ok && symbol.Kind != SYMBOL_BUILTIN && symbol.Kind != SYMBOL_TYPE {
+		return append(locations, s.location(document.URI, symbol.Ident))
+	}
+	if !ok && !isUnresolved(document.Resolution, ident) {
+		// members and other names the resolver does not bind
+		return locations
+	}
+	declarations := s.declarations(document, ident)
+	if ok && symbol.Kind == SYMBOL_BUILTIN {
+		// a library function may be declared in a script of the workspace as well
+		declarations = s.Workspace.Declarations(ident.Name)
+	}
+	for _, declaration := range declarations {
+		locations = append(locations, s.location(declaration.URI, declaration.Symbol.Ident))
+	}
+	if len(locations) == 0 && ok && symbol.Kind == SYMBOL_BUILTIN {
+		location, err := s.Catalog.Declaration(s.DeclarationDir, ident.Name)
+		if err != nil {
+			logger.Printf("Cannot declare %s: %s", ident.Name, err)
+			return locations
+		}
+		locations = append(locations, location)
+	}
+	return locations
+}
+
+// location converts the range of a node in an indexed file for the client.
+func (s *State) location(uri string, node Node) lsp.Location {
+	r := NodeRange(node)
+	if file, ok := s.Workspace.Files[uri]; ok {
+		r = file.Document.RangeToClient(r)
+	}
+	return lsp.Location{URI: uri, Range: r}
+}
+
+// References finds the uses of the name under the cursor. Locals and parameters are used in
+// their file only, functions, globals and builtins anywhere in the workspace.
+func (s *State) References(logger *log.Logger, id int, params lsp.ReferenceParams) lsp.ReferencesResponse {
+	locations := []lsp.Location{}
+	if document, ok := s.Documents[NormalizeURI(params.TextDocument.URI)]; ok {
+		locations = s.references(logger, document, document.FromClient(params.Position), params.Context.IncludeDeclaration)
+	}
+	return lsp.ReferencesResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id: &id,
 		},
+		Result: locations,
 	}
 }
 
-func (s *State) CodeAction(id int, uri string) lsp.CodeActionResponse {
+func (s *State) references(logger *log.Logger, document *Document, pos lsp.Position, includeDeclaration bool) []lsp.Location {
+	locations := []lsp.Location{}
+	node, _ := document.Tree.NodeAt(pos)
+	ident, ok := node.(*Ident)
+	if !ok {
+		return locations
+	}
+	symbol, ok := document.Resolution.Symbols[ident]
+	switch {
+	case ok && (symbol.Kind == SYMBOL_LOCAL || symbol.Kind == SYMBOL_PARAM):
+		if includeDeclaration {
+			locations = append(locations, s.location(document.URI, symbol.Ident))
+		}
+		for _, use := range symbol.Uses {
+			locations = append(locations, s.location(document.URI, use))
+		}
+		return locations
+	case ok && symbol.Kind == SYMBOL_TYPE:
+		return locations
+	case !ok && !isUnresolved(document.Resolution, ident):
+		return locations
+	}
+
+	if ok && symbol.Kind == SYMBOL_BUILTIN {
+		if includeDeclaration {
+			if location, err := s.Catalog.Declaration(s.DeclarationDir, ident.Name); err == nil {
+				locations = append(locations, location)
+			} else {
+				logger.Printf("Cannot declare %s: %s", ident.Name, err)
+			}
+		}
+		for _, reference := range s.Workspace.BuiltinReferences(ident.Name) {
+			locations = append(locations, s.location(reference.URI, reference.Ident))
+		}
+		return locations
+	}
+
+	seen := map[*Ident]bool{}
+	for _, declaration := range s.declarations(document, ident) {
+		if includeDeclaration {
+			locations = append(locations, s.location(declaration.URI, declaration.Symbol.Ident))
+		}
+		for _, reference := range s.Workspace.References(declaration) {
+			if !seen[reference.Ident] {
+				seen[reference.Ident] = true
+				locations = append(locations, s.location(reference.URI, reference.Ident))
+			}
+		}
+	}
+	return locations
+}
+
+// declarations finds the functions or global variables an identifier refers to: the one of its
+// own file, or the ones visible through the includes when the file does not declare it.
+func (s *State) declarations(document *Document, ident *Ident) []Declaration {
+	symbol, ok := document.Resolution.Symbols[ident]
+	if ok && (symbol.Kind == SYMBOL_FUNCTION || symbol.Kind == SYMBOL_GLOBAL) {
+		return []Declaration{{URI: document.URI, Symbol: symbol}}
+	}
+	if !ok && isUnresolved(document.Resolution, ident) {
+		return s.Workspace.Visible(document.URI, ident.Name)
+	}
+	return []Declaration{}
+}
+
+func isUnresolved(resolution *Resolution, ident *Ident) bool {
+	for _, unresolved := range resolution.Unresolved {
+		if unresolved == ident {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) CodeAction(id int, params lsp.CodeActionParams) lsp.CodeActionResponse {
 	actions := []lsp.CodeAction{}
+	if document, ok := s.Documents[NormalizeURI(params.TextDocument.URI)]; ok {
+		r := document.RangeFromClient(params.Range)
+		actions = append(actions, s.includeActions(document, r)...)
+		actions = append(actions, s.unusedIncludeActions(document, r)...)
+	}
 
 	return lsp.CodeActionResponse {
 		Response: lsp.Response {
@@ -104,26 +509,93 @@ func (s *State) CodeAction(id int, uri string) lsp.CodeActionResponse {
 	}
 }
 
-func (s *State) Completion(id int, uri string) lsp.CompletionResponse {
-	items := []lsp.CompletionItem{{
-		Label: "Deez nuts",
-		Kind: 1,
-		Detail: "Deez nuts in your mouth",
-		Documentation: "Skibideez",
-		AdditionalTextEdits: []lsp.TextEdit{{
-			Range: LineRange(0, 0, 0),
-			NewText: "#include \"deez\"\n",
-		}},
-	}}
-	
-	response := lsp.CompletionResponse {
-		Response: lsp.Response {
+// includeActions add the includes missing for the uses of library functions in r, one action per library.
+func (s *State) includeActions(document *Document, r lsp.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	index := map[string]int{}
+	for _, missing := range s.Workspace.MissingIncludes(document.URI) {
+		if PositionLess(missing.Ident.End(), r.Start) || PositionLess(r.End, missing.Ident.Pos()) {
+			continue
+		}
+		diagnostic := s.problemDiagnostic(document, missing.Problem())
+		library := missing.Function.Library()
+		if !s.Workspace.Includable(document.URI, library) {
+			continue
+		}
+		if i, ok := index[library]; ok {
+			actions[i].Diagnostics = append(actions[i].Diagnostics, diagnostic)
+			continue
+		}
+		line := IncludeInsertLine(document.Tree.File)
+		index[library] = len(actions)
+		actions = append(actions, lsp.CodeAction{
+			Title: fmt.Sprintf("Add #include \"%s\"", library),
+			Kind: lsp.CODE_ACTION_QUICKFIX,
+			Diagnostics: []lsp.Diagnostic{diagnostic},
+			Edit: &lsp.WorkspaceEdit{
+				Changes: map[string][]lsp.TextEdit{
+					document.URI: {{Range: LineRange(line, 0, 0), NewText: fmt.Sprintf("#include \"%s\"\n", library)}},
+				},
+			},
+		})
+	}
+	return actions
+}
+
+// unusedIncludeActions remove the unused includes in r, and all unused includes of the document at once.
+func (s *State) unusedIncludeActions(document *Document, r lsp.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	unused := s.Workspace.UnusedIncludes(document.URI)
+	all := []lsp.TextEdit{}
+	diagnostics := []lsp.Diagnostic{}
+	for _, include := range unused {
+		edit := lsp.TextEdit{Range: DirectiveLines(include.Directive), NewText: ""}
+		diagnostic := s.problemDiagnostic(document, UnusedIncludeProblem(include))
+		all = append(all, edit)
+		diagnostics = append(diagnostics, diagnostic)
+		if PositionLess(include.Directive.End(), r.Start) || PositionLess(r.End, include.Directive.Pos()) {
+			continue
+		}
+		actions = append(actions, lsp.CodeAction{
+			Title: fmt.Sprintf("Remove unused #include \"%s\"", include.Directive.Path),
+			Kind: lsp.CODE_ACTION_QUICKFIX,
+			Diagnostics: []lsp.Diagnostic{diagnostic},
+			Edit: &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{document.URI: {edit}}},
+		})
+	}
+	if len(actions) > 0 && len(unused) > 1 {
+		actions = append(actions, lsp.CodeAction{
+			Title: "Remove all unused includes",
+			Kind: lsp.CODE_ACTION_QUICKFIX,
+			Diagnostics: diagnostics,
+			Edit: &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{document.URI: all}},
+		})
+	}
+	return actions
+}
+
+// DeadCodeReport lists the dead functions and global variables of the whole workspace.
+func (s *State) DeadCodeReport(id int) lsp.DeadCodeResponse {
+	entries := []lsp.DeadCodeEntry{}
+	for _, dead := range s.Workspace.AllDeadCode(s.EntryPoints) {
+		kind := "global"
+		if dead.Symbol.Kind == SYMBOL_FUNCTION {
+			kind = "function"
+		}
+		entries = append(entries, lsp.DeadCodeEntry{
+			Location: lsp.Location{URI: dead.URI, Range: s.Workspace.Files[dead.URI].Document.RangeToClient(NodeRange(dead.Symbol.Ident))},
+			Kind: kind,
+			Name: dead.Symbol.Name,
+			Message: dead.Problem().Message,
+		})
+	}
+	return lsp.DeadCodeResponse{
+		Response: lsp.Response{
 			RPC: "2.0",
 			Id: &id,
 		},
-		Result: items,
+		Result: entries,
 	}
-	return response
 }
 
 func LineRange(line, start, end int) lsp.Range {
@@ -138,3 +610,38 @@ func LineRange(line, start, end int) lsp.Range {
 		},
 	}
 }
+
+func nodeTypeName(node Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*analysis.")
+}
+
+func describeNode(node Node) string {
+	switch n := node.(type) {
+	case *Ident:
+		return fmt.Sprintf("Name: %s", n.Name)
+	case *BasicLit:
+		return fmt.Sprintf("Value: %s", n.Value)
+	case *TypeExpr:
+		return fmt.Sprintf("Type: %s", n)
+	case *IncludeDirective:
+		return fmt.Sprintf("Path: %s", n.Path)
+	}
+	return fmt.Sprintf("Line: %d Col: %d", node.Pos().Line, node.Pos().Character)
+}
+
+func describeSymbol(resolution *Resolution, ident *Ident) string {
+	symbol, ok := resolution.Symbols[ident]
+	if !ok {
+		if isUnresolved(resolution, ident) {
+			return " (unresolved)"
+		}
+		return ""
+	}
+	if symbol.Ident == ident {
+		return fmt.Sprintf(" (declares %s)", symbol.Kind)
+	}
+	if symbol.Ident == nil {
+		return fmt.Sprintf(" (refers to %s)", symbol.Kind)
+	}
+	return fmt.Sprintf(" (refers to %s declared at Line: %d Col: %d)", symbol.Kind, symbol.Ident.Pos().Line, symbol.Ident.Pos().Character)
+}
@@ -5,69 +5,118 @@ import (
 	"strings"
 )
 
-type Token struct {
-	pos lsp.Position
-	value string
+type TokenKind int
+
+const (
+	TOKEN_ILLEGAL TokenKind = iota
+	TOKEN_EOF
+	TOKEN_IDENT
+	TOKEN_KEYWORD
+	TOKEN_STRING
+	TOKEN_NUMBER
+	TOKEN_OPERATOR
+	TOKEN_PUNCT
+	TOKEN_COMMENT
+	TOKEN_DIRECTIVE
+)
+
+var tokenKindNames = map[TokenKind]string{
+	TOKEN_ILLEGAL:   "illegal",
+	TOKEN_EOF:       "end of file",
+	TOKEN_IDENT:     "identifier",
+	TOKEN_KEYWORD:   "keyword",
+	TOKEN_STRING:    "string",
+	TOKEN_NUMBER:    "number",
+	TOKEN_OPERATOR:  "operator",
+	TOKEN_PUNCT:     "punctuation",
+	TOKEN_COMMENT:   "comment",
+	TOKEN_DIRECTIVE: "directive",
 }
 
-func Tokenize(text string) []Token {
-	tokens := []Token{}
-	sb := strings.Builder{}
+func (k TokenKind) String() string {
+	return tokenKindNames[k]
+}
 
-	for i, line := range strings.Split(text, "\n") {
-		for j := 0; j < len(line); j++ {
-			if line[j] != ' ' {
-				sb.WriteByte(line[j])
-				continue
-			}
-			if sb.Len() == 0 {
-				continue
-			}
-			pos := lsp.Position{Line:i, Character: j+1-sb.Len()}
-			tokens = append(tokens, Token{pos: pos, value: sb.String()})
-			sb.Reset()
-		}
-		if sb.Len() == 0 {
-			continue
-		}
-		pos := lsp.Position{Line:i, Character: len(line)-sb.Len()}
-		tokens = append(tokens, Token{pos: pos, value: sb.String()})
-		sb.Reset()
-	}
-	return tokens
+var keywords = map[string]bool{
+	"function": true,
+	"if":       true,
+	"else":     true,
+	"while":    true,
+	"for":      true,
+	"return":   true,
+	"break":    true,
+	"continue": true,
+	"true":     true,
+	"false":    true,
+	"callback": true,
 }
 
-func GetTokensToNewLine(tokens []Token) []Token {
-	idx := 0
-	for idx < len(tokens) && tokens[idx].pos.Line == tokens[0].pos.Line {
-		idx++
-	}
-	return tokens[:idx] 
+func IsKeyword(value string) bool {
+	return keywords[value]
 }
 
-func Stringify(tokens []Token) string {
-	value := strings.Builder{}
-	for i, token := range tokens {
-		if i > 0 {
-			value.WriteByte(' ')
-		}
-		value.WriteString(token.value)
-	}
-	return value.String()
+type Token struct {
+	Kind TokenKind
+	// Value is the exact source text of the token, quotes and comment markers included.
+	Value string
+	Start lsp.Position
+	End lsp.Position
+	// Offset is the byte offset of the first character of the token.
+	Offset int
+}
+
+func (t Token) EndOffset() int {
+	return t.Offset + len(t.Value)
+}
+
+func (t Token) Is(kind TokenKind, value string) bool {
+	return t.Kind == kind && t.Value == value
+}
+
+func (t Token) IsComment() bool {
+	return t.Kind == TOKEN_COMMENT
 }
 
-func GetStartPos(tokens... Token) lsp.Position {
-	return lsp.Position{
-		Line:tokens[0].pos.Line, 
-		Character: tokens[0].pos.Character,
+// Tokenize returns every token of the text including comments, without the trailing EOF token.
+func Tokenize(text string) []Token {
+	lexer := NewLexer(text)
+	tokens := []Token{}
+	for {
+		token := lexer.Next()
+		if token.Kind == TOKEN_EOF {
+			return tokens
+		}
+		tokens = append(tokens, token)
 	}
 }
 
-func GetFinalPos(tokens... Token) lsp.Position {
-	idx := len(tokens)-1
-	finalPos := lsp.Position {
-		Line: tokens[idx].pos.Line,
-		Character:tokens[idx].pos.Character + len(tokens[idx].value),
+// Unquote returns the content of a string literal with its escape sequences resolved.
+// Unknown escape sequences are kept as written, so windows paths survive unchanged.
+func Unquote(literal string) string {
+	literal = strings.TrimPrefix(literal, "\"")
+	sb := strings.Builder{}
+	for i := 0; i < len(literal); i++ {
+		if literal[i] == '"' {
+			break
+		}
+		if literal[i] != '\\' || i+1 == len(literal) {
+			sb.WriteByte(literal[i])
+			continue
+		}
+		i++
+		switch literal[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case '"', '\\', '\'':
+			sb.WriteByte(literal[i])
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(literal[i])
+		}
 	}
-	return finalPos
+	return sb.String()
 }
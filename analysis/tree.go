@@ -0,0 +1,179 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+)
+
+// NodeID identifies a node within one Tree. Ids are handed out in walk order,
+// so the File is always 0 and a parent always has a smaller id than its children.
+type NodeID int
+
+const NoNode NodeID = -1
+
+// Tree is an index over a parsed file. The AST nodes stay untouched,
+// the tree keeps parents and children for every node in flat tables.
+type Tree struct {
+	File *File
+	nodes []Node
+	parents []NodeID
+	children [][]NodeID
+	ids map[Node]NodeID
+}
+
+func NewTree(file *File) *Tree {
+	t := &Tree{
+		File: file,
+		ids: map[Node]NodeID{},
+	}
+	stack := []NodeID{}
+	Inspect(file, func(node Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		id := NodeID(len(t.nodes))
+		parent := NoNode
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+			t.children[parent] = append(t.children[parent], id)
+		}
+		t.nodes = append(t.nodes, node)
+		t.parents = append(t.parents, parent)
+		t.children = append(t.children, nil)
+		t.ids[node] = id
+		stack = append(stack, id)
+		return true
+	})
+	return t
+}
+
+func (t *Tree) Len() int {
+	return len(t.nodes)
+}
+
+func (t *Tree) Node(id NodeID) Node {
+	if id < 0 || int(id) >= len(t.nodes) {
+		return nil
+	}
+	return t.nodes[id]
+}
+
+func (t *Tree) ID(node Node) (NodeID, bool) {
+	id, ok := t.ids[node]
+	return id, ok
+}
+
+// Parent returns the node directly enclosing node, nil for the file or a node of another tree.
+func (t *Tree) Parent(node Node) Node {
+	id, ok := t.ids[node]
+	if !ok {
+		return nil
+	}
+	return t.Node(t.parents[id])
+}
+
+func (t *Tree) Children(node Node) []Node {
+	id, ok := t.ids[node]
+	if !ok {
+		return nil
+	}
+	children := []Node{}
+	for _, child := range t.children[id] {
+		children = append(children, t.nodes[child])
+	}
+	return children
+}
+
+// Siblings returns the children of the parent of node, node included.
+func (t *Tree) Siblings(node Node) []Node {
+	parent := t.Parent(node)
+	if parent == nil {
+		return nil
+	}
+	return t.Children(parent)
+}
+
+func (t *Tree) NextSibling(node Node) Node {
+	return t.sibling(node, 1)
+}
+
+func (t *Tree) PrevSibling(node Node) Node {
+	return t.sibling(node, -1)
+}
+
+func (t *Tree) sibling(node Node, step int) Node {
+	siblings := t.Siblings(node)
+	for i, sibling := range siblings {
+		if sibling != node {
+			continue
+		}
+		if i+step < 0 || i+step >= len(siblings) {
+			return nil
+		}
+		return siblings[i+step]
+	}
+	return nil
+}
+
+// Ancestors returns the nodes enclosing node, innermost first, the file last.
+func (t *Tree) Ancestors(node Node) []Node {
+	ancestors := []Node{}
+	for parent := t.Parent(node); parent != nil; parent = t.Parent(parent) {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// EnclosingFunc returns the function declaration node belongs to, nil outside of functions.
+func (t *Tree) EnclosingFunc(node Node) *FuncDecl {
+	if function, ok := node.(*FuncDecl); ok {
+		return function
+	}
+	for _, ancestor := range t.Ancestors(node) {
+		if function, ok := ancestor.(*FuncDecl); ok {
+			return function
+		}
+	}
+	return nil
+}
+
+// Path returns the nodes containing pos, outermost first. The last node is the innermost one.
+func (t *Tree) Path(pos lsp.Position) []Node {
+	path := []Node{}
+	if t.File == nil || !Contains(t.File, pos) {
+		return path
+	}
+	id := NodeID(0)
+	for {
+		path = append(path, t.nodes[id])
+		next := NoNode
+		for _, child := range t.children[id] {
+			if Contains(t.nodes[child], pos) {
+				// prefer the later child when two touch at pos, it starts right there
+				next = child
+			}
+		}
+		if next == NoNode {
+			return path
+		}
+		id = next
+	}
+}
+
+// NodeAt returns the innermost node containing pos.
+func (t *Tree) NodeAt(pos lsp.Position) (Node, bool) {
+	path := t.Path(pos)
+	if len(path) == 0 {
+		return nil, false
+	}
+	return path[len(path)-1], true
+}
+
+// EnclosingFuncAt returns the function declaration containing pos, nil outside of functions.
+func (t *Tree) EnclosingFuncAt(pos lsp.Position) *FuncDecl {
+	node, found := t.NodeAt(pos)
+	if !found {
+		return nil
+	}
+	return t.EnclosingFunc(node)
+}
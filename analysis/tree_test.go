@@ -0,0 +1,46 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestTreeParents(t *testing.T) {
+	file, _ := analysis.Parse("test.sct", "long g;\n\nlong function f(long a) {\n\tif (a > 0) {\n\t\treturn g + a;\n\t}\n\treturn 0;\n}")
+	tree := analysis.NewTree(file)
+
+	node, found := tree.NodeAt(lsp.Position{Line: 4, Character: 13})
+	if !found {
+		t.Fatalf("Expected: a node at 4:13")
+	}
+	ident, ok := node.(*analysis.Ident)
+	if !ok || ident.Name != "a" {
+		t.Fatalf("Expected: identifier a, Actual: %#v", node)
+	}
+
+	binary, ok := tree.Parent(ident).(*analysis.BinaryExpr)
+	if !ok || binary.Op != "+" {
+		t.Fatalf("Expected: binary + as parent, Actual: %#v", tree.Parent(ident))
+	}
+	if tree.PrevSibling(ident) != binary.X {
+		t.Fatalf("Expected: g as previous sibling")
+	}
+
+	function := tree.EnclosingFunc(ident)
+	if function == nil || function.Name.Name != "f" {
+		t.Fatalf("Expected: enclosing function f, Actual: %#v", function)
+	}
+	ancestors := tree.Ancestors(ident)
+	if ancestors[len(ancestors)-1] != file {
+		t.Fatalf("Expected: the file as outermost ancestor")
+	}
+	if tree.EnclosingFuncAt(lsp.Position{Line: 0, Character: 5}) != nil {
+		t.Fatalf("Expected: no function around the global")
+	}
+
+	id, _ := tree.ID(ident)
+	if tree.Node(id) != ident {
+		t.Fatalf("Expected: the node behind its id to be the node itself")
+	}
+}
@@ -0,0 +1,413 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Types are written the way TypeExpr prints them, with the names of builtin types in lower case:
+// `long`, `list<long>`, `[]string`. The empty string is a type the checker does not know,
+// it is compatible with everything so one unknown never causes a chain of reports.
+const (
+	TYPE_UNKNOWN = ""
+	TYPE_VOID = "void"
+	TYPE_BOOL = "bool"
+	TYPE_LONG = "long"
+	TYPE_DOUBLE = "double"
+	TYPE_STRING = "string"
+	TYPE_VARIANT = "variant"
+	TYPE_CALLBACK = "callback"
+)
+
+var numericTypes = map[string]bool{
+	"short": true,
+	"int": true,
+	"long": true,
+	"double": true,
+}
+
+// TypeName normalizes a written type. The catalog is not consistent about the case of
+// builtin types, `BOOL`, `Bool` and `bool` are the same type.
+func TypeName(typ *TypeExpr) string {
+	if typ == nil {
+		return TYPE_UNKNOWN
+	}
+	if typ.Array {
+		return "[]" + TypeName(typ.Args[0])
+	}
+	name := typ.Name
+	if IsBuiltinType(strings.ToLower(name)) || strings.EqualFold(name, TYPE_CALLBACK) {
+		name = strings.ToLower(name)
+	}
+	if len(typ.Args) == 0 {
+		return name
+	}
+	args := []string{}
+	for _, arg := range typ.Args {
+		args = append(args, TypeName(arg))
+	}
+	return name + "<" + strings.Join(args, ",") + ">"
+}
+
+// Assignable reports whether a value of type from can be stored where type to is expected.
+// Numbers convert into each other and into bool, classes compare by name.
+func Assignable(from, to string) bool {
+	switch {
+	case from == TYPE_UNKNOWN || to == TYPE_UNKNOWN:
+		return true
+	case from == TYPE_VOID || to == TYPE_VOID:
+		return false
+	case from == to || from == TYPE_VARIANT || to == TYPE_VARIANT:
+		return true
+	case (numericTypes[from] || from == TYPE_BOOL) && (numericTypes[to] || to == TYPE_BOOL):
+		return true
+	}
+	// a generic type without arguments matches every instance of it
+	fromBase, _, fromGeneric := strings.Cut(from, "<")
+	toBase, _, toGeneric := strings.Cut(to, "<")
+	if fromGeneric != toGeneric {
+		return fromBase == toBase
+	}
+	return strings.EqualFold(from, to)
+}
+
+// elementType is the type indexing a value of typ gives.
+func elementType(typ string) string {
+	if strings.HasPrefix(typ, "[]") {
+		return typ[2:]
+	}
+	base, args, ok := strings.Cut(typ, "<")
+	if !ok {
+		return TYPE_UNKNOWN
+	}
+	args = strings.TrimSuffix(args, ">")
+	switch base {
+	case "list", "arraylist":
+		return args
+	case "map":
+		if parts := splitTypeArgs(args); len(parts) == 2 {
+			return parts[1]
+		}
+	}
+	return TYPE_UNKNOWN
+}
+
+// splitTypeArgs splits a list of type arguments at the commas outside of angle brackets.
+func splitTypeArgs(args string) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i, c := range args {
+		switch c {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, args[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, args[start:])
+}
+
+// Signature is what a call needs to know about a function.
+type Signature struct {
+	Name string
+	Result string
+	Params []BormParam
+}
+
+func (s Signature) String() string {
+	params := []string{}
+	for _, param := range s.Params {
+		params = append(params, param.String())
+	}
+	text := fmt.Sprintf("%s(%s)", s.Name, strings.Join(params, ", "))
+	if s.Result != "" {
+		text = s.Result + " " + text
+	}
+	return text
+}
+
+// FuncSignature is the signature of a function declared in a script.
+func FuncSignature(function *FuncDecl) Signature {
+	signature := Signature{Name: function.Name.Name, Result: TypeName(function.Type), Params: []BormParam{}}
+	for _, param := range function.Params {
+		signature.Params = append(signature.Params, BormParam{Type: TypeName(param.Type), Name: param.Name.Name, ByRef: param.ByRef})
+	}
+	return signature
+}
+
+// BuiltinSignature is the signature of a catalog entry.
+func BuiltinSignature(function *BormFunction) Signature {
+	return Signature{Name: function.Name, Result: function.ReturnType, Params: function.Params}
+}
+
+// TypeInfo holds the types the checker found.
+type TypeInfo struct {
+	// Types maps every expression to its type.
+	Types map[Expr]string
+	Problems []Problem
+}
+
+// Check infers the type of every expression of a resolved file and reports values used
+// where their type does not fit: in declarations, assignments, returns, conditions and calls.
+func Check(file *File, resolution *Resolution) *TypeInfo {
+	c := &checker{
+		info: &TypeInfo{Types: map[Expr]string{}},
+		resolution: resolution,
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *FuncDecl:
+			c.function = d
+			if d.Body != nil {
+				c.stmt(d.Body)
+			}
+		case *VarDecl:
+			c.varDecl(d)
+		}
+	}
+	return c.info
+}
+
+type checker struct {
+	info *TypeInfo
+	resolution *Resolution
+	function *FuncDecl
+}
+
+func (c *checker) report(node Node, format string, args ...any) {
+	c.info.Problems = append(c.info.Problems, Problem{
+		Range: NodeRange(node),
+		Severity: SEVERITY_ERROR,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// assign reports value if it does not fit into a place of type to.
+func (c *checker) assign(value Expr, to string, context string) {
+	from := c.expr(value)
+	if from == TYPE_VOID {
+		c.report(value, "expression has no value, it cannot be used %s", context)
+	} else if !Assignable(from, to) {
+		c.report(value, "cannot use %s as %s %s", from, to, context)
+	}
+}
+
+func (c *checker) varDecl(decl *VarDecl) {
+	if decl.Value != nil {
+		c.assign(decl.Value, TypeName(decl.Type), fmt.Sprintf("in declaration of '%s'", decl.Name.Name))
+	}
+}
+
+func (c *checker) condition(cond Expr) {
+	if cond == nil {
+		return
+	}
+	typ := c.expr(cond)
+	if typ != TYPE_BOOL && !numericTypes[typ] && typ != TYPE_UNKNOWN && typ != TYPE_VARIANT {
+		c.report(cond, "condition must be bool, found %s", typ)
+	}
+}
+
+func (c *checker) stmt(stmt Stmt) {
+	switch s := stmt.(type) {
+	case nil:
+	case *BlockStmt:
+		for _, stmt := range s.List {
+			c.stmt(stmt)
+		}
+	case *VarDecl:
+		c.varDecl(s)
+	case *ExprStmt:
+		c.expr(s.X)
+	case *IfStmt:
+		c.condition(s.Cond)
+		c.stmt(s.Body)
+		c.stmt(s.Else)
+	case *WhileStmt:
+		c.condition(s.Cond)
+		c.stmt(s.Body)
+	case *ForStmt:
+		c.stmt(s.Init)
+		c.condition(s.Cond)
+		c.stmt(s.Post)
+		c.stmt(s.Body)
+	case *ReturnStmt:
+		c.returnStmt(s)
+	}
+}
+
+func (c *checker) returnStmt(s *ReturnStmt) {
+	result := TypeName(c.function.Type)
+	name := c.function.Name.Name
+	switch {
+	case s.Result == nil && result != TYPE_VOID && result != TYPE_UNKNOWN:
+		c.report(s, "missing return value, function '%s' returns %s", name, result)
+	case s.Result != nil && result == TYPE_VOID:
+		c.expr(s.Result)
+		c.report(s.Result, "function '%s' returns void, it cannot return a value", name)
+	case s.Result != nil:
+		c.assign(s.Result, result, fmt.Sprintf("in return from '%s'", name))
+	}
+}
+
+func (c *checker) expr(expr Expr) string {
+	if expr == nil {
+		return TYPE_UNKNOWN
+	}
+	typ := c.infer(expr)
+	c.info.Types[expr] = typ
+	return typ
+}
+
+func (c *checker) infer(expr Expr) string {
+	switch e := expr.(type) {
+	case *BasicLit:
+		switch {
+		case e.Kind == TOKEN_STRING:
+			return TYPE_STRING
+		case e.Kind == TOKEN_KEYWORD:
+			return TYPE_BOOL
+		case strings.HasPrefix(e.Value, "0x") || strings.HasPrefix(e.Value, "0X"):
+			return TYPE_LONG
+		case strings.ContainsAny(e.Value, ".eE"):
+			return TYPE_DOUBLE
+		}
+		return TYPE_LONG
+	case *Ident:
+		return c.identType(e)
+	case *CallExpr:
+		return c.call(e)
+	case *CallbackExpr:
+		return TYPE_CALLBACK
+	case *ParenExpr:
+		return c.expr(e.X)
+	case *UnaryExpr:
+		typ := c.expr(e.X)
+		if e.Op == "!" {
+			return TYPE_BOOL
+		}
+		return typ
+	case *BinaryExpr:
+		return c.binary(e)
+	case *AssignExpr:
+		target := c.expr(e.Target)
+		if e.Op == "=" {
+			c.assign(e.Value, target, "in assignment")
+		} else if value := c.expr(e.Value); !(target == TYPE_STRING && e.Op == "+=") && !Assignable(value, target) {
+			c.report(e.Value, "cannot use %s as %s in assignment", value, target)
+		}
+		return target
+	case *IndexExpr:
+		typ := c.expr(e.X)
+		c.expr(e.Index)
+		return elementType(typ)
+	case *MemberExpr:
+		// members of classes are not known
+		c.expr(e.X)
+	}
+	return TYPE_UNKNOWN
+}
+
+func (c *checker) identType(ident *Ident) string {
+	symbol, ok := c.resolution.Symbols[ident]
+	if !ok {
+		return TYPE_UNKNOWN
+	}
+	switch symbol.Kind {
+	case SYMBOL_GLOBAL, SYMBOL_PARAM, SYMBOL_LOCAL:
+		return TypeName(symbol.Type)
+	case SYMBOL_BUILTIN:
+		// catalog constants are entries without parameters
+		if len(symbol.Builtin.Params) == 0 {
+			return symbol.Builtin.ReturnType
+		}
+	}
+	return TYPE_UNKNOWN
+}
+
+func (c *checker) binary(e *BinaryExpr) string {
+	x, y := c.expr(e.X), c.expr(e.Y)
+	switch e.Op {
+	case "&&", "||", "==", "!=", "<", "<=", ">", ">=":
+		return TYPE_BOOL
+	case "+":
+		if x == TYPE_STRING || y == TYPE_STRING {
+			return TYPE_STRING
+		}
+	}
+	switch {
+	case x == TYPE_DOUBLE || y == TYPE_DOUBLE:
+		return TYPE_DOUBLE
+	case numericTypes[x] && numericTypes[y]:
+		return TYPE_LONG
+	}
+	return TYPE_UNKNOWN
+}
+
+func (c *checker) call(call *CallExpr) string {
+	ident, ok := call.Fun.(*Ident)
+	if !ok {
+		c.expr(call.Fun)
+		c.args(call.Args)
+		return TYPE_UNKNOWN
+	}
+	symbol, ok := c.resolution.Symbols[ident]
+	if !ok {
+		c.args(call.Args)
+		return TYPE_UNKNOWN
+	}
+
+	switch symbol.Kind {
+	case SYMBOL_TYPE:
+		// conversions take any value
+		c.args(call.Args)
+		return strings.ToLower(symbol.Name)
+	case SYMBOL_FUNCTION:
+		signature := FuncSignature(symbol.Decl.(*FuncDecl))
+		c.checkArgs(call, signature)
+		return signature.Result
+	case SYMBOL_BUILTIN:
+		signature := BuiltinSignature(symbol.Builtin)
+		c.checkArgs(call, signature)
+		return signature.Result
+	}
+	c.args(call.Args)
+	return TYPE_UNKNOWN
+}
+
+func (c *checker) args(args []Expr) {
+	for _, arg := range args {
+		c.expr(arg)
+	}
+}
+
+func (c *checker) checkArgs(call *CallExpr, signature Signature) {
+	for i, arg := range call.Args {
+		if i >= len(signature.Params) {
+			c.expr(arg)
+			continue
+		}
+		param := signature.Params[i]
+		c.assign(arg, param.Type, fmt.Sprintf("for parameter %s of '%s'", paramName(param, i), signature.Name))
+	}
+	switch {
+	case len(call.Args) > len(signature.Params):
+		c.report(call.Args[len(signature.Params)], "too many arguments in call to '%s': expected %d, found %d",
+			signature.Name, len(signature.Params), len(call.Args))
+	case len(call.Args) < len(signature.Params):
+		c.report(call, "not enough arguments in call to '%s': expected %d, found %d",
+			signature.Name, len(signature.Params), len(call.Args))
+	}
+}
+
+func paramName(param BormParam, i int) string {
+	if param.Name == "" {
+		return fmt.Sprintf("%d", i+1)
+	}
+	return fmt.Sprintf("'%s'", param.Name)
+}
@@ -0,0 +1,104 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"testing"
+)
+
+func checkScript(t *testing.T, text string) *analysis.TypeInfo {
+	file, errors := analysis.Parse("file:///test.sct", text)
+	if len(errors) != 0 {
+		t.Fatalf("Expected: no errors, Actual: %v", errors)
+	}
+	catalog := analysis.NewCatalog([]analysis.BormFunction{
+		analysis.NewBormFunction("Global", "Programm", "string", "GetName", "long __lId", ""),
+		analysis.NewBormFunction("Global", "Programm", "long", "CDM_LEFT", "", ""),
+		analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text, string title", ""),
+	})
+	return analysis.Check(file, analysis.Resolve(file, catalog))
+}
+
+func TestCheckValidScript(t *testing.T) {
+	info := checkScript(t, `
+list<long> ids;
+long function Count(list<long> values, double factor) {
+	long n = CDM_LEFT + 1;
+	double d = n * factor;
+	string s = "n = " + n;
+	for (long i = 0; i < 10; i++) {
+		n += ids[i];
+	}
+	if (n > 0 && !(d == 0)) {
+		MsgBox(GetName(n), string(d));
+	}
+	return n;
+}
+void function main() {
+	Count(ids, 2);
+	return;
+}
+`)
+	if len(info.Problems) != 0 {
+		t.Fatalf("Expected: no problems, Actual: %v", info.Problems)
+	}
+}
+
+func TestCheckMismatches(t *testing.T) {
+	info := checkScript(t, `
+void function main() {
+	long id = "one";
+	GetName("one");
+	string s = 1;
+	s = MsgBox("a", "b");
+	if (s) {
+		return 1;
+	}
+	GetName();
+}
+long function Value() {
+	return;
+}
+`)
+	expected := []string{
+		"cannot use string as long in declaration of 'id'",
+		"cannot use string as long for parameter '__lId' of 'GetName'",
+		"cannot use long as string in declaration of 's'",
+		"expression has no value, it cannot be used in assignment",
+		"condition must be bool, found string",
+		"function 'main' returns void, it cannot return a value",
+		"not enough arguments in call to 'GetName': expected 1, found 0",
+		"missing return value, function 'Value' returns long",
+	}
+	if len(info.Problems) != len(expected) {
+		t.Fatalf("Expected: %d problems, Actual: %v", len(expected), info.Problems)
+	}
+	for i, problem := range info.Problems {
+		if problem.Message != expected[i] {
+			t.Fatalf("Expected: %s, Actual: %s", expected[i], problem.Message)
+		}
+	}
+}
+
+func TestAssignable(t *testing.T) {
+	cases := []struct {
+		from, to string
+		expected bool
+	}{
+		{"long", "double", true},
+		{"bool", "long", true},
+		{"string", "long", false},
+		{"list<long>", "list<long>", true},
+		{"list<long>", "list<string>", false},
+		{"list", "list<string>", true},
+		{"list<long>", "arraylist<long>", false},
+		{"GridCellPosition", "gridcellposition", true},
+		{"variant", "Json", true},
+		{"void", "long", false},
+		{"", "long", true},
+	}
+	for _, c := range cases {
+		if analysis.Assignable(c.from, c.to) != c.expected {
+			t.Fatalf("Expected: %s to %s %v, Actual: %v", c.from, c.to, c.expected, !c.expected)
+		}
+	}
+}
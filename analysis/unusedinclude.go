@@ -0,0 +1,73 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+	"fmt"
+)
+
+// UnusedIncludes returns the includes of a file from which the file uses nothing: no function
+// or global variable declared in the included file or the files it includes, and no catalog
+// function of those libraries. Includes that cannot be resolved are left alone.
+func (w *Workspace) UnusedIncludes(uri string) []Include {
+	unused := []Include{}
+	file, ok := w.Files[uri]
+	if !ok {
+		return unused
+	}
+	names := map[string]bool{}
+	for _, ident := range file.Resolution.Unresolved {
+		names[ident.Name] = true
+	}
+	libraries := map[string]bool{}
+	for _, symbol := range file.Resolution.Symbols {
+		if symbol.Kind == SYMBOL_BUILTIN && symbol.Builtin.Library() != "" {
+			libraries[libraryName(symbol.Builtin.Library())] = true
+		}
+	}
+
+	for _, include := range file.Includes {
+		if include.URI != "" && include.URI != uri && !w.provides(include.URI, uri, names, libraries) {
+			unused = append(unused, include)
+		}
+	}
+	return unused
+}
+
+// provides reports whether an included file, or one it includes, declares one of names or is one of libraries.
+func (w *Workspace) provides(included, uri string, names, libraries map[string]bool) bool {
+	for _, other := range append([]string{included}, w.IncludedFiles(included)...) {
+		if other == uri {
+			continue
+		}
+		if libraries[libraryName(URIToPath(other))] {
+			return true
+		}
+		indexed, ok := w.Files[other]
+		if !ok {
+			continue
+		}
+		for name := range indexed.Resolution.Root.Symbols {
+			if names[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func UnusedIncludeProblem(include Include) Problem {
+	return Problem{
+		Range: NodeRange(include.Directive),
+		Severity: SEVERITY_WARNING,
+		Message: fmt.Sprintf("'%s' is included but not used", include.Directive.Path),
+		Tags: []Tag{TAG_UNNECESSARY},
+	}
+}
+
+// DirectiveLines is the range of the lines an include directive takes, removing it leaves no empty line.
+func DirectiveLines(directive *IncludeDirective) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: directive.Pos().Line, Character: 0},
+		End: lsp.Position{Line: directive.End().Line + 1, Character: 0},
+	}
+}
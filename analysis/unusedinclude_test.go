@@ -0,0 +1,45 @@
+package analysis_test
+
+import (
+	"borm-lsp/lsp"
+	"testing"
+)
+
+func TestUnusedIncludes(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"used.sct": "long function Used() {\n\treturn 1;\n}\n",
+		"other.sct": "long function Other() {\n\treturn 1;\n}\n",
+		"empty.sct": "",
+	})
+	main := scriptURI(state, "main.sct")
+	text := "#include \"used.sct\"\n" +
+		"#include \"other.sct\"\n" +
+		"#include \"<BD>\\BIN\\Borm.sct\"\n" +
+		"#include \"empty\"\n" +
+		"void function main() {\n" +
+		"\tUsed();\n" +
+		"}\n"
+	all := state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: text})
+	diagnostics := all[0].Diagnostics
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected: 3 diagnostics, Actual: %v", diagnostics)
+	}
+	if diagnostics[1].Message != "'other.sct' is included but not used" || diagnostics[2].Message != "'empty' is included but not used" {
+		t.Fatalf("Expected: other.sct and empty unused, Actual: %v", diagnostics)
+	}
+
+	actions := state.CodeAction(1, lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: main},
+		Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 3}, End: lsp.Position{Line: 1, Character: 3}},
+	}).Result
+	if len(actions) != 2 || actions[0].Title != "Remove unused #include \"other.sct\"" || actions[1].Title != "Remove all unused includes" {
+		t.Fatalf("Expected: remove other.sct and remove all, Actual: %v", actions)
+	}
+	edits := actions[0].Edit.Changes[main]
+	if len(edits) != 1 || edits[0].Range.Start.Line != 1 || edits[0].Range.End.Line != 2 {
+		t.Fatalf("Expected: line 1 removed, Actual: %v", edits)
+	}
+	if edits := actions[1].Edit.Changes[main]; len(edits) != 2 || edits[1].Range.Start.Line != 3 || edits[1].Range.End.Line != 4 {
+		t.Fatalf("Expected: lines 1 and 3 removed, Actual: %v", edits)
+	}
+}
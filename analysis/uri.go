@@ -0,0 +1,43 @@
+package analysis
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// URIToPath turns a file URI into a path of this system, "" for other schemes.
+// Windows paths come as `file:///c%3A/Scripts/x.sct`.
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// PathToURI is the reverse of URIToPath. Drive letters are written in lower case.
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if len(path) >= 2 && path[1] == ':' {
+		path = strings.ToLower(path[:1]) + path[1:]
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// NormalizeURI brings a file URI of the client into the form PathToURI writes, which files are
+// indexed by: percent-decoded and with a lower case drive letter. Other URIs stay unchanged.
+func NormalizeURI(uri string) string {
+	path := URIToPath(uri)
+	if path == "" {
+		return uri
+	}
+	return PathToURI(path)
+}
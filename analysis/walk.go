@@ -0,0 +1,182 @@
+package analysis
+
+import (
+	"borm-lsp/lsp"
+)
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the syntax tree in depth-first order, children in source order. The comments
+// of a file are visited as children of the innermost node enclosing them.
+func Walk(v Visitor, node Node) {
+	(&walker{}).walk(v, node)
+}
+
+// walker holds the comments of the file being walked not visited yet, in source order.
+type walker struct {
+	comments []*Comment
+}
+
+// before visits the comments starting before pos.
+func (c *walker) before(v Visitor, pos lsp.Position) {
+	for len(c.comments) > 0 && PositionLess(c.comments[0].Pos(), pos) {
+		comment := c.comments[0]
+		c.comments = c.comments[1:]
+		if w := v.Visit(comment); w != nil {
+			w.Visit(nil)
+		}
+	}
+}
+
+// skip drops the comments starting before pos, they are in a subtree that is not visited.
+func (c *walker) skip(pos lsp.Position) {
+	for len(c.comments) > 0 && PositionLess(c.comments[0].Pos(), pos) {
+		c.comments = c.comments[1:]
+	}
+}
+
+func (c *walker) walk(v Visitor, node Node) {
+	c.before(v, node.Pos())
+	if v = v.Visit(node); v == nil {
+		c.skip(node.End())
+		return
+	}
+
+	switch n := node.(type) {
+	case *File:
+		c.comments = n.Comments
+		for _, decl := range n.Decls {
+			c.walk(v, decl)
+		}
+		// the comments behind the last declaration, the file may end before them
+		if len(c.comments) > 0 {
+			c.before(v, c.comments[len(c.comments)-1].End())
+		}
+
+	case *Comment, *IncludeDirective, *BadDecl, *Ident, *BasicLit, *BadExpr,
+	*EmptyStmt, *BranchStmt, *BadStmt:
+		// no children
+
+	case *FuncDecl:
+		if n.Type != nil {
+			c.walk(v, n.Type)
+		}
+		c.walk(v, n.Name)
+		for _, param := range n.Params {
+			c.walk(v, param)
+		}
+		if n.Body != nil {
+			c.walk(v, n.Body)
+		}
+
+	case *Param:
+		c.walk(v, n.Type)
+		c.walk(v, n.Name)
+
+	case *VarDecl:
+		c.walk(v, n.Type)
+		c.walk(v, n.Name)
+		if n.Value != nil {
+			c.walk(v, n.Value)
+		}
+
+	case *TypeExpr:
+		for _, arg := range n.Args {
+			c.walk(v, arg)
+		}
+
+	case *CallExpr:
+		c.walk(v, n.Fun)
+		for _, arg := range n.Args {
+			c.walk(v, arg)
+		}
+
+	case *CallbackExpr:
+		c.walk(v, n.Name)
+
+	case *ParenExpr:
+		c.walk(v, n.X)
+
+	case *UnaryExpr:
+		c.walk(v, n.X)
+
+	case *BinaryExpr:
+		c.walk(v, n.X)
+		c.walk(v, n.Y)
+
+	case *AssignExpr:
+		c.walk(v, n.Target)
+		c.walk(v, n.Value)
+
+	case *IndexExpr:
+		c.walk(v, n.X)
+		c.walk(v, n.Index)
+
+	case *MemberExpr:
+		c.walk(v, n.X)
+		c.walk(v, n.Member)
+
+	case *BlockStmt:
+		for _, stmt := range n.List {
+			c.walk(v, stmt)
+		}
+
+	case *ExprStmt:
+		c.walk(v, n.X)
+
+	case *IfStmt:
+		c.walk(v, n.Cond)
+		c.walk(v, n.Body)
+		if n.Else != nil {
+			c.walk(v, n.Else)
+		}
+
+	case *WhileStmt:
+		c.walk(v, n.Cond)
+		c.walk(v, n.Body)
+
+	case *ForStmt:
+		if n.Init != nil {
+			c.walk(v, n.Init)
+		}
+		if n.Cond != nil {
+			c.walk(v, n.Cond)
+		}
+		if n.Post != nil {
+			c.walk(v, n.Post)
+		}
+		c.walk(v, n.Body)
+
+	case *ReturnStmt:
+		if n.Result != nil {
+			c.walk(v, n.Result)
+		}
+
+	default:
+		panic("analysis.Walk: unexpected node type")
+	}
+
+	c.before(v, node.End())
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the syntax tree in depth-first order: it starts by calling f(node);
+// if f returns true, Inspect invokes f recursively for each of the children of node,
+// followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,303 @@
+package analysis
+
+import (
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IndexedFile is a script of the workspace. For open documents it is the state of the editor,
+// for all others the file on disk.
+type IndexedFile struct {
+	URI string
+	Document *Document
+	Tree *Tree
+	Resolution *Resolution
+	Includes []Include
+	Open bool
+}
+
+// Declaration is a function or global variable declared in a file of the workspace.
+type Declaration struct {
+	URI string
+	Symbol *Symbol
+}
+
+// Reference is an identifier in a file of the workspace referring to a top level name.
+type Reference struct {
+	URI string
+	Ident *Ident
+}
+
+// Workspace indexes every script under the workspace folders and the scripts they include.
+// Queries go over all files, so an edit only ever has to update its own file.
+type Workspace struct {
+	Roots []string
+	Files map[string]*IndexedFile
+	Config IncludeConfig
+	// Encoding is given to the documents read from disk.
+	Encoding PositionEncoding
+	// references is built on the first query after a change, see referenceIndex.
+	references *referenceIndex
+}
+
+// referenceIndex holds the uses of top level names no single file knows the declaration of.
+type referenceIndex struct {
+	// unresolved maps a file and a name to the uses of that name other files could not
+	// resolve themselves but that lead to the declaration in the file.
+	unresolved map[string]map[string][]Reference
+	// builtins maps a catalog name to its uses.
+	builtins map[string][]Reference
+}
+
+func NewWorkspace() *Workspace {
+	return &Workspace{Files: map[string]*IndexedFile{}}
+}
+
+func IsScript(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".sct")
+}
+
+// Contains reports whether a path lies within one of the workspace folders.
+func (w *Workspace) Contains(path string) bool {
+	for _, root := range w.Roots {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan reads all scripts below the workspace folders. Files open in the editor are kept.
+func (w *Workspace) Scan(logger *log.Logger, catalog *Catalog) {
+	for _, root := range w.Roots {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				logger.Printf("Cannot scan %s: %s", path, err)
+				return nil
+			}
+			if !entry.IsDir() && IsScript(path) {
+				w.Load(logger, PathToURI(path), catalog)
+			}
+			return nil
+		})
+	}
+	logger.Printf("Indexed %d scripts", len(w.Files))
+}
+
+// Load reads a script from disk, unless it is open. A file that cannot be read is dropped.
+func (w *Workspace) Load(logger *log.Logger, uri string, catalog *Catalog) {
+	if file, ok := w.Files[uri]; ok && file.Open {
+		return
+	}
+	text, err := ReadTextFile(URIToPath(uri))
+	if err != nil {
+		logger.Printf("Cannot read %s: %s", uri, err)
+		delete(w.Files, uri)
+		return
+	}
+	w.references = nil
+	document := NewDocument(uri, "borm", 0, text)
+	document.Encoding = w.Encoding
+	includes, _ := ResolveIncludes(document.Tree.File, w.Config)
+	w.Files[uri] = &IndexedFile{
+		URI: uri,
+		Document: document,
+		Tree: document.Tree,
+		Resolution: Resolve(document.Tree.File, catalog),
+		Includes: includes,
+	}
+	w.LoadIncludes(logger, includes, catalog)
+}
+
+// LoadIncludes reads the included files that are not indexed yet, scripts outside of the
+// workspace folders like the ones of the BORM installation among them.
+func (w *Workspace) LoadIncludes(logger *log.Logger, includes []Include, catalog *Catalog) {
+	for _, include := range includes {
+		if _, ok := w.Files[include.URI]; include.URI != "" && !ok {
+			w.Load(logger, include.URI, catalog)
+		}
+	}
+}
+
+// Configure changes where included files are searched and resolves the includes of all files again.
+func (w *Workspace) Configure(logger *log.Logger, config IncludeConfig, catalog *Catalog) {
+	w.Config = config
+	w.references = nil
+	for _, uri := range w.uris() {
+		file := w.Files[uri]
+		file.Includes, _ = ResolveIncludes(file.Tree.File, config)
+		w.LoadIncludes(logger, file.Includes, catalog)
+	}
+}
+
+// Remove drops a deleted script, unless it is open.
+func (w *Workspace) Remove(uri string) {
+	if file, ok := w.Files[uri]; ok && !file.Open {
+		delete(w.Files, uri)
+		w.references = nil
+	}
+}
+
+// Update makes the index follow an open document.
+func (w *Workspace) Update(document *Document) {
+	w.references = nil
+	w.Files[document.URI] = &IndexedFile{
+		URI: document.URI,
+		Document: document,
+		Tree: document.Tree,
+		Resolution: document.Resolution,
+		Includes: document.Includes,
+		Open: true,
+	}
+}
+
+// Closed goes back to the file on disk, if it belongs to the workspace or is included.
+func (w *Workspace) Closed(logger *log.Logger, uri string, catalog *Catalog) {
+	delete(w.Files, uri)
+	w.references = nil
+	if path := URIToPath(uri); path != "" && IsScript(path) && (w.Contains(path) || w.included(uri)) {
+		w.Load(logger, uri, catalog)
+	}
+}
+
+func (w *Workspace) included(uri string) bool {
+	for _, file := range w.Files {
+		for _, include := range file.Includes {
+			if include.URI == uri {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// uris returns the indexed files in a stable order.
+func (w *Workspace) uris() []string {
+	uris := []string{}
+	for uri := range w.Files {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// Declarations returns every function and global variable called name.
+func (w *Workspace) Declarations(name string) []Declaration {
+	declarations := []Declaration{}
+	for _, uri := range w.uris() {
+		if symbol, ok := w.Files[uri].Resolution.Root.Symbols[name]; ok {
+			declarations = append(declarations, Declaration{URI: uri, Symbol: symbol})
+		}
+	}
+	return declarations
+}
+
+// Visible returns the declarations a name the file could not resolve itself refers to: the
+// ones in the files it includes, or every one in the workspace when none of them declares it.
+func (w *Workspace) Visible(uri, name string) []Declaration {
+	return w.visible(w.IncludedFiles(uri), name)
+}
+
+// visible is Visible for the files a file includes.
+func (w *Workspace) visible(includes []string, name string) []Declaration {
+	visible := []Declaration{}
+	for _, included := range includes {
+		if symbol, ok := w.Files[included].Resolution.Root.Symbols[name]; ok {
+			visible = append(visible, Declaration{URI: included, Symbol: symbol})
+		}
+	}
+	if len(visible) == 0 {
+		return w.Declarations(name)
+	}
+	return visible
+}
+
+// TypeNames are the class types written in the scripts of the workspace, sorted.
+func (w *Workspace) TypeNames() []string {
+	seen := map[string]bool{}
+	for _, file := range w.Files {
+		Inspect(file.Tree.File, func(node Node) bool {
+			if typ, ok := node.(*TypeExpr); ok && typ.Name != "" && !IsBuiltinType(strings.ToLower(typ.Name)) && !strings.EqualFold(typ.Name, TYPE_CALLBACK) {
+				seen[typ.Name] = true
+			}
+			return true
+		})
+	}
+	names := []string{}
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// References returns the identifiers referring to a declaration: the uses its own file binds to
+// it and the uses other files could not resolve themselves but Visible leads to it.
+func (w *Workspace) References(declaration Declaration) []Reference {
+	references := []Reference{}
+	for _, ident := range declaration.Symbol.Uses {
+		references = append(references, Reference{URI: declaration.URI, Ident: ident})
+	}
+	references = append(references, w.index().unresolved[declaration.URI][declaration.Symbol.Name]...)
+	return sortReferences(references)
+}
+
+// BuiltinReferences returns the uses of a catalog entry in every file.
+func (w *Workspace) BuiltinReferences(name string) []Reference {
+	return sortReferences(append([]Reference{}, w.index().builtins[name]...))
+}
+
+func sortReferences(references []Reference) []Reference {
+	sort.SliceStable(references, func(i, j int) bool {
+		if references[i].URI != references[j].URI {
+			return references[i].URI < references[j].URI
+		}
+		return PositionLess(references[i].Ident.Pos(), references[j].Ident.Pos())
+	})
+	return references
+}
+
+// index builds the reference index once after each change of the workspace, so queries over
+// all declarations do not scan every file again.
+func (w *Workspace) index() *referenceIndex {
+	if w.references != nil {
+		return w.references
+	}
+	index := &referenceIndex{unresolved: map[string]map[string][]Reference{}, builtins: map[string][]Reference{}}
+	for _, uri := range w.uris() {
+		resolution := w.Files[uri].Resolution
+		for name, symbol := range resolution.builtins {
+			if symbol.Kind != SYMBOL_BUILTIN {
+				continue
+			}
+			for _, ident := range symbol.Uses {
+				index.builtins[name] = append(index.builtins[name], Reference{URI: uri, Ident: ident})
+			}
+		}
+		if len(resolution.Unresolved) == 0 {
+			continue
+		}
+		includes := w.IncludedFiles(uri)
+		visible := map[string][]Declaration{}
+		for _, ident := range resolution.Unresolved {
+			declarations, ok := visible[ident.Name]
+			if !ok {
+				declarations = w.visible(includes, ident.Name)
+				visible[ident.Name] = declarations
+			}
+			for _, declaration := range declarations {
+				names, ok := index.unresolved[declaration.URI]
+				if !ok {
+					names = map[string][]Reference{}
+					index.unresolved[declaration.URI] = names
+				}
+				names[ident.Name] = append(names[ident.Name], Reference{URI: uri, Ident: ident})
+			}
+		}
+	}
+	w.references = index
+	return index
+}
@@ -0,0 +1,107 @@
+package analysis_test
+
+import (
+	"borm-lsp/analysis"
+	"borm-lsp/lsp"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScript(t *testing.T, path, text string) string {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return analysis.PathToURI(path)
+}
+
+var logger = log.New(io.Discard, "", 0)
+
+// newWorkspaceState writes the scripts, named relative to a new workspace folder, and starts
+// a state on that folder. The catalog holds the functions given, if any.
+func newWorkspaceState(t *testing.T, files map[string]string, catalog ...analysis.BormFunction) *analysis.State {
+	root := t.TempDir()
+	for name, text := range files {
+		writeScript(t, filepath.Join(root, filepath.FromSlash(name)), text)
+	}
+	state := analysis.NewState()
+	state.DeclarationDir = t.TempDir()
+	if len(catalog) > 0 {
+		state.Catalog = analysis.NewCatalog(catalog)
+	}
+	state.Initialize(logger, lsp.InitializeRequestParams{WorkspaceFolders: []lsp.WorkspaceFolder{{URI: analysis.PathToURI(root)}}})
+	return &state
+}
+
+// scriptURI is the URI of a script in the workspace folder of the state.
+func scriptURI(state *analysis.State, name string) string {
+	return analysis.PathToURI(filepath.Join(state.Workspace.Roots[0], filepath.FromSlash(name)))
+}
+
+func TestWorkspaceIndex(t *testing.T) {
+	state := newWorkspaceState(t, map[string]string{
+		"lib/Library.SCT": "long function Shared() {\n\treturn 1;\n}\n",
+		"notes.txt": "long function NotAScript() {}",
+	}, analysis.NewBormFunction("Global", "Programm", "void", "MsgBox", "string text, string title", ""))
+	library := scriptURI(state, "lib/Library.SCT")
+	if len(state.Workspace.Files) != 1 {
+		t.Fatalf("Expected: 1 indexed script, Actual: %d", len(state.Workspace.Files))
+	}
+
+	main := scriptURI(state, "Main.sct")
+	all := state.OpenDocument(logger, lsp.TextDocumentItem{URI: main, Version: 1, Text: "#include \"lib/Library.SCT\"\nvoid function main() {\n\tShared();\n\tMissing();\n}\n"})
+	if len(all[0].Diagnostics) != 1 || all[0].Diagnostics[0].Message != "'Missing' is not declared" {
+		t.Fatalf("Expected: only Missing reported, Actual: %v", all[0].Diagnostics)
+	}
+
+	declarations := state.Workspace.Declarations("Shared")
+	if len(declarations) != 1 || declarations[0].URI != library {
+		t.Fatalf("Expected: Shared declared in Library.SCT, Actual: %v", declarations)
+	}
+	references := state.Workspace.References(declarations[0])
+	if len(references) != 1 || references[0].URI != main {
+		t.Fatalf("Expected: 1 reference in Main.sct, Actual: %v", references)
+	}
+
+	// the library goes away on disk
+	os.Remove(analysis.URIToPath(library))
+	all = state.WatchedFilesChanged(logger, []lsp.FileEvent{{URI: library, Type: lsp.FILE_DELETED}})
+	if len(all) != 1 || len(all[0].Diagnostics) != 3 {
+		t.Fatalf("Expected: Shared reported after deletion, Actual: %v", all)
+	}
+}
+
+func TestEncodedURI(t *testing.T) {
+	if analysis.NormalizeURI("file:///C%3A/Scripts/x.sct") != "file:///c:/Scripts/x.sct" || analysis.PathToURI("C:/Scripts/x.sct") != "file:///c:/Scripts/x.sct" {
+		t.Fatalf("Expected: file:///c:/Scripts/x.sct, Actual: %s", analysis.NormalizeURI("file:///C%3A/Scripts/x.sct"))
+	}
+
+	state := newWorkspaceState(t, map[string]string{
+		"scripts:v1/lib.sct": "long function Shared() {\n\treturn 1;\n}\n",
+		"scripts:v1/main.sct": "",
+	})
+	main := scriptURI(state, "scripts:v1/main.sct")
+
+	// the client escapes the colon the workspace keeps as it is
+	encoded := strings.Replace(main, "scripts:v1", "scripts%3Av1", 1)
+	state.OpenDocument(logger, lsp.TextDocumentItem{URI: encoded, Version: 1, Text: "#include \"lib.sct\"\nvoid function main() {\n\tShared();\n}\n"})
+	if len(state.Workspace.Files) != 2 || !state.Workspace.Files[main].Open {
+		t.Fatalf("Expected: main.sct indexed once as open, Actual: %v", state.Workspace.Files)
+	}
+	locations := state.References(logger, 1, lsp.ReferenceParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: encoded},
+			Position: lsp.Position{Line: 2, Character: 2},
+		},
+		Context: lsp.ReferenceContext{IncludeDeclaration: true},
+	}).Result
+	if len(locations) != 2 {
+		t.Fatalf("Expected: the declaration and one use, Actual: %v", locations)
+	}
+}
@@ -7,6 +7,28 @@ type InitializeRequest struct {
 
 type InitializeRequestParams struct {
 	ClientInfo *ClientInfo `json:"clientInfo"`
+	Capabilities ClientCapabilities `json:"capabilities"`
+	RootURI *string `json:"rootUri"`
+	InitializationOptions *Settings `json:"initializationOptions"`
+	WorkspaceFolders []WorkspaceFolder `json:"workspaceFolders"`
+}
+
+type ClientCapabilities struct {
+	General *GeneralClientCapabilities `json:"general,omitempty"`
+	Workspace *WorkspaceClientCapabilities `json:"workspace,omitempty"`
+}
+
+type WorkspaceClientCapabilities struct {
+	DidChangeWatchedFiles *DynamicRegistrationCapability `json:"didChangeWatchedFiles,omitempty"`
+}
+
+type DynamicRegistrationCapability struct {
+	DynamicRegistration bool `json:"dynamicRegistration"`
+}
+
+type GeneralClientCapabilities struct {
+	// PositionEncodings lists the encodings the client supports, most preferred first.
+	PositionEncodings []string `json:"positionEncodings,omitempty"`
 }
 
 type ClientInfo struct {
@@ -25,11 +47,32 @@ type InitializeResult struct {
 }
 
 type ServerCapabilities struct {
+	PositionEncoding string `json:"positionEncoding,omitempty"`
 	TextDocumentSync int `json:"textDocumentSync"`
 	HoverProvider bool `json:"hoverProvider"`
 	DefinitionProvider bool `json:"definitionProvider"`
+	ReferencesProvider bool `json:"referencesProvider"`
+	RenameProvider RenameOptions `json:"renameProvider"`
 	CodeActionProvider bool `json:"codeActionProvider"` 
-	CompletionProvider map[string]any `json:"completionProvider"`
+	CompletionProvider CompletionOptions `json:"completionProvider"`
+	SignatureHelpProvider SignatureHelpOptions `json:"signatureHelpProvider"`
+	ExecuteCommandProvider ExecuteCommandOptions `json:"executeCommandProvider"`
+}
+
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider"`
+}
+
+type CompletionOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+type SignatureHelpOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type ExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
 }
 
 type ServerInfo struct {
@@ -37,7 +80,7 @@ type ServerInfo struct {
 	Version string `json:"version"`
 }
 
-func NewInitializeResponse(id int) InitializeResponse {
+func NewInitializeResponse(id int, positionEncoding string) InitializeResponse {
 	return InitializeResponse {
 		Response: Response {
 			RPC: "2.0",
@@ -45,11 +88,20 @@ func NewInitializeResponse(id int) InitializeResponse {
 		},
 		Result: InitializeResult {
 			Capabilities: ServerCapabilities{
-				TextDocumentSync: 1,
+				PositionEncoding: positionEncoding,
+				TextDocumentSync: 2,
 				HoverProvider: true,
 				DefinitionProvider: true,
+				ReferencesProvider: true,
+				RenameProvider: RenameOptions{PrepareProvider: true},
 				CodeActionProvider: true,
-				CompletionProvider: map[string]any{},
+				CompletionProvider: CompletionOptions{ResolveProvider: true},
+				SignatureHelpProvider: SignatureHelpOptions{
+					TriggerCharacters: []string{"(", ","},
+				},
+				ExecuteCommandProvider: ExecuteCommandOptions{
+					Commands: []string{COMMAND_DEAD_CODE_REPORT},
+				},
 			},
 			ServerInfo: ServerInfo{
 				Name: "bormlsp",
@@ -11,6 +11,22 @@ type Response struct {
 	Id *int `json:"id,omitempty"`
 }
 
+// ErrorResponse answers a request that failed.
+type ErrorResponse struct {
+	Response
+	Error ResponseError `json:"error"`
+}
+
+type ResponseError struct {
+	Code int `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	ERROR_INVALID_PARAMS = -32602
+	ERROR_REQUEST_FAILED = -32803
+)
+
 type Notification struct {
 	RPC string `json:"jsonrpc"`
 	Method string `json:"method"`
@@ -5,7 +5,7 @@ package lsp
  */
 type TextDocumentItem struct {
 	URI string `json:"uri"`
-	LanguageId string `json:"lanuageId"`
+	LanguageId string `json:"languageId"`
 	Version int `json:"version"`
 	Text string `json:"text"`
 }
@@ -48,7 +48,19 @@ type DidOpenTextDocumentNotification struct {
 }
 
 type DidOpenTextDocumentParams struct {
-	TextDocument TextDocumentItem
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+/**
+ * Document Close Notification
+ */
+type DidCloseTextDocumentNotification struct {
+	Notification
+	Params DidCloseTextDocumentParams `json:"params"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
 }
 
 /**
@@ -64,7 +76,10 @@ type DidChangeTextDocumentParams struct {
 	ContentChanges []TextDocumentChangeEvent `json:"contentChanges"`
 }
 
+// TextDocumentChangeEvent replaces Range with Text. Without a range Text is the whole document.
 type TextDocumentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	RangeLength int `json:"rangeLength,omitempty"`
 	Text string `json:"text"`
 }
 
@@ -78,6 +93,8 @@ type DiagnosticNotification struct {
 
 type DiagnosticParams struct {
 	URI string `json:"uri"`
+	// Version is the document version the diagnostics were computed for.
+	Version *int `json:"version,omitempty"`
 	Diagnostics []Diagnostic `json:"diagnostics"`
 }
 
@@ -86,6 +103,13 @@ type Diagnostic struct {
 	Severity int `json:"severity"` 
 	Source string `json:"source"` 
 	Message string `json:"message"` 
+	Tags []int `json:"tags,omitempty"`
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type DiagnosticRelatedInformation struct {
+	Location Location `json:"location"`
+	Message string `json:"message"`
 }
 
 /**
@@ -123,7 +147,96 @@ type DefinitionParams struct {
 
 type DefinitionResponse struct {
 	Response
-	Result Location `json:"result"` 
+	Result []Location `json:"result"` 
+}
+
+/**
+ * References Request
+ */
+type ReferencesRequest struct {
+	Request
+	Params ReferenceParams `json:"params"`
+}
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context ReferenceContext `json:"context"`
+}
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type ReferencesResponse struct {
+	Response
+	Result []Location `json:"result"`
+}
+
+/**
+ * Rename Request
+ */
+type PrepareRenameRequest struct {
+	Request
+	Params TextDocumentPositionParams `json:"params"`
+}
+
+type PrepareRenameResponse struct {
+	Response
+	Result *PrepareRenameResult `json:"result"`
+}
+
+type PrepareRenameResult struct {
+	Range Range `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
+type RenameRequest struct {
+	Request
+	Params RenameParams `json:"params"`
+}
+
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+type RenameResponse struct {
+	Response
+	Result *WorkspaceEdit `json:"result"`
+}
+
+/**
+ * Signature Help Request
+ */
+type SignatureHelpRequest struct {
+	Request
+	Params SignatureHelpParams `json:"params"`
+}
+
+type SignatureHelpParams struct {
+	TextDocumentPositionParams
+}
+
+type SignatureHelpResponse struct {
+	Response
+	Result *SignatureHelp `json:"result"`
+}
+
+type SignatureHelp struct {
+	Signatures []SignatureInformation `json:"signatures"`
+	ActiveSignature int `json:"activeSignature"`
+	ActiveParameter int `json:"activeParameter"`
+}
+
+type SignatureInformation struct {
+	Label string `json:"label"`
+	Documentation string `json:"documentation,omitempty"`
+	Parameters []ParameterInformation `json:"parameters"`
+}
+
+type ParameterInformation struct {
+	// Label are the start and end offset of the parameter within the signature label.
+	Label [2]int `json:"label"`
 }
 
 /**
@@ -146,10 +259,15 @@ type CodeActionResponse struct {
 }
 
 type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
 }
 
+const CODE_ACTION_QUICKFIX = "quickfix"
+
 type CodeAction struct {
 	Title string `json:"title"` 
+	Kind string `json:"kind,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
 	Edit *WorkspaceEdit `json:"edit,omitempty"` 
 	Command *Command `json:"command,omitempty"` 
 }
@@ -186,10 +304,38 @@ type CompletionResponse struct {
 	Result []CompletionItem `json:"result"` 
 }
 
+const (
+	COMPLETION_KIND_FUNCTION = 3
+	COMPLETION_KIND_VARIABLE = 6
+	COMPLETION_KIND_CLASS = 7
+	COMPLETION_KIND_KEYWORD = 14
+	COMPLETION_KIND_CONSTANT = 21
+)
+
 type CompletionItem struct {
 	Label string `json:"label"` 
 	Kind int `json:"kind"` 
 	Detail string `json:"detail"` 
-	Documentation string `json:"documentation"` 
+	Documentation string `json:"documentation,omitempty"` 
 	AdditionalTextEdits []TextEdit `json:"additionalTextEdits,omitempty"` 
+	// Data comes back with completionItem/resolve.
+	Data *CompletionItemData `json:"data,omitempty"`
+}
+
+type CompletionItemData struct {
+	// Builtin names the catalog entry whose description the item shows once resolved.
+	Builtin string `json:"builtin,omitempty"`
+}
+
+/**
+ * Completion Item Resolve Request
+ */
+type CompletionItemResolveRequest struct {
+	Request
+	Params CompletionItem `json:"params"`
+}
+
+type CompletionItemResolveResponse struct {
+	Response
+	Result CompletionItem `json:"result"`
 }
@@ -0,0 +1,127 @@
+package lsp
+
+type WorkspaceFolder struct {
+	URI string `json:"uri"`
+	Name string `json:"name"`
+}
+
+/**
+ * Configuration
+ */
+// Settings configure the server, sent as initializationOptions or
+// in the "bormlsp" section of workspace/didChangeConfiguration.
+type Settings struct {
+	// BaseDirectory is the BORM installation directory `<BD>` stands for.
+	BaseDirectory string `json:"baseDirectory"`
+	IncludePaths []string `json:"includePaths"`
+	// Callbacks are functions the runtime calls by name, they count as used.
+	Callbacks []string `json:"callbacks"`
+}
+
+type DidChangeConfigurationNotification struct {
+	Notification
+	Params DidChangeConfigurationParams `json:"params"`
+}
+
+type DidChangeConfigurationParams struct {
+	Settings struct {
+		Bormlsp *Settings `json:"bormlsp"`
+	} `json:"settings"`
+}
+
+/**
+ * Capability Registration
+ */
+type RegistrationRequest struct {
+	Request
+	Params RegistrationParams `json:"params"`
+}
+
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+type Registration struct {
+	Id string `json:"id"`
+	Method string `json:"method"`
+	RegisterOptions any `json:"registerOptions,omitempty"`
+}
+
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+type FileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"`
+}
+
+func NewWatchedFilesRegistration(id int, globPattern string) RegistrationRequest {
+	return RegistrationRequest{
+		Request: Request{
+			RPC: "2.0",
+			Id: id,
+			Method: "client/registerCapability",
+		},
+		Params: RegistrationParams{
+			Registrations: []Registration{{
+				Id: "bormlsp-watched-files",
+				Method: "workspace/didChangeWatchedFiles",
+				RegisterOptions: DidChangeWatchedFilesRegistrationOptions{
+					Watchers: []FileSystemWatcher{{GlobPattern: globPattern}},
+				},
+			}},
+		},
+	}
+}
+
+/**
+ * Watched Files Notification
+ */
+const (
+	FILE_CREATED = 1
+	FILE_CHANGED = 2
+	FILE_DELETED = 3
+)
+
+type DidChangeWatchedFilesNotification struct {
+	Notification
+	Params DidChangeWatchedFilesParams `json:"params"`
+}
+
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
+}
+
+type FileEvent struct {
+	URI string `json:"uri"`
+	Type int `json:"type"`
+}
+
+/**
+ * Execute Command Request
+ */
+const COMMAND_DEAD_CODE_REPORT = "bormlsp.deadCodeReport"
+
+type ExecuteCommandRequest struct {
+	Request
+	Params ExecuteCommandParams `json:"params"`
+}
+
+type ExecuteCommandParams struct {
+	Command string `json:"command"`
+	Arguments []any `json:"arguments,omitempty"`
+}
+
+type DeadCodeResponse struct {
+	Response
+	Result []DeadCodeEntry `json:"result"`
+}
+
+// DeadCodeEntry is a function nothing calls or a global variable nothing reads.
+type DeadCodeEntry struct {
+	Location Location `json:"location"`
+	// Kind is "function" or "global".
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Message string `json:"message"`
+}
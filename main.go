@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 )
 
 func main() {
@@ -20,6 +21,7 @@ func main() {
 	scanner.Split(rpc.Split)
 
 	state := analysis.NewState()
+	state.LoadCatalog(logger, catalogPath())
 	writer := os.Stdout
 
 	for scanner.Scan() {
@@ -29,11 +31,11 @@ func main() {
 			logger.Printf("Got an error: %s", err)
 			continue
 		}
-		handleMessage(logger, writer, state, method, contents)
+		handleMessage(logger, writer, &state, method, contents)
 	}
 }
 
-func handleMessage(logger *log.Logger, writer io.Writer, state analysis.State, method string, contents []byte) {
+func handleMessage(logger *log.Logger, writer io.Writer, state *analysis.State, method string, contents []byte) {
 	logger.Printf("Received msg with method: %s", method)
 
 	switch method {
@@ -47,9 +49,15 @@ func handleMessage(logger *log.Logger, writer io.Writer, state analysis.State, m
 			request.Params.ClientInfo.Name, 
 			request.Params.ClientInfo.Version)
 
-		msg := lsp.NewInitializeResponse(request.Id)
+		state.Initialize(logger, request.Params)
+		msg := lsp.NewInitializeResponse(request.Id, string(state.Encoding))
 		writeResponse(writer, msg)
 
+	case "initialized":
+		if state.WatchFiles {
+			writeResponse(writer, lsp.NewWatchedFilesRegistration(1, "**/*.sct"))
+		}
+
 	case "textDocument/didOpen":
 		var request lsp.DidOpenTextDocumentNotification
 		if err := json.Unmarshal(contents, &request); err != nil {
@@ -59,17 +67,7 @@ func handleMessage(logger *log.Logger, writer io.Writer, state analysis.State, m
 
 		logger.Printf("Opened: %s", request.Params.TextDocument.URI)
 
-		diagnostics := state.OpenDocument(logger, request.Params.TextDocument.URI, request.Params.TextDocument.Text)
-		writeResponse(writer, lsp.DiagnosticNotification{
-			Notification: lsp.Notification{
-				RPC: "2.0",
-				Method: "textDocument/publishDiagnostics",
-			},
-			Params: lsp.DiagnosticParams{
-				URI: request.Params.TextDocument.URI,
-				Diagnostics: diagnostics,
-			},
-		})
+		publishDiagnostics(writer, state.OpenDocument(logger, request.Params.TextDocument))
 
 	case "textDocument/didChange":
 		var request lsp.DidChangeTextDocumentNotification
@@ -80,20 +78,55 @@ func handleMessage(logger *log.Logger, writer io.Writer, state analysis.State, m
 
 		logger.Printf("Changed: %s", request.Params.TextDocument.URI)
 
-		for _, change := range request.Params.ContentChanges {
-			diagnostics := state.UpdateDocument(logger, request.Params.TextDocument.URI, change.Text)
-			writeResponse(writer, lsp.DiagnosticNotification{
-				Notification: lsp.Notification{
-					RPC: "2.0",
-					Method: "textDocument/publishDiagnostics",
-				},
-				Params: lsp.DiagnosticParams{
-					URI: request.Params.TextDocument.URI,
-					Diagnostics: diagnostics,
-				},
+		publishDiagnostics(writer, state.UpdateDocument(logger, request.Params.TextDocument, request.Params.ContentChanges))
+
+	case "textDocument/didClose":
+		var request lsp.DidCloseTextDocumentNotification
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("textDocument/didClose: %s", err)
+			return 
+		}
+
+		logger.Printf("Closed: %s", request.Params.TextDocument.URI)
+
+		publishDiagnostics(writer, state.CloseDocument(logger, request.Params.TextDocument.URI))
+
+	case "workspace/didChangeConfiguration":
+		var request lsp.DidChangeConfigurationNotification
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("workspace/didChangeConfiguration: %s", err)
+			return 
+		}
+
+		publishDiagnostics(writer, state.ChangeConfiguration(logger, request.Params.Settings.Bormlsp))
+
+	case "workspace/executeCommand":
+		var request lsp.ExecuteCommandRequest
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("workspace/executeCommand: %s", err)
+			return 
+		}
+
+		switch request.Params.Command {
+		case lsp.COMMAND_DEAD_CODE_REPORT:
+			writeResponse(writer, state.DeadCodeReport(request.Id))
+		default:
+			logger.Printf("Unknown command: %s", request.Params.Command)
+			writeResponse(writer, lsp.ErrorResponse{
+				Response: lsp.Response{RPC: "2.0", Id: &request.Id},
+				Error: lsp.ResponseError{Code: lsp.ERROR_INVALID_PARAMS, Message: "unknown command " + request.Params.Command},
 			})
 		}
 
+	case "workspace/didChangeWatchedFiles":
+		var request lsp.DidChangeWatchedFilesNotification
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("workspace/didChangeWatchedFiles: %s", err)
+			return 
+		}
+
+		publishDiagnostics(writer, state.WatchedFilesChanged(logger, request.Params.Changes))
+
 	case "textDocument/hover":
 		var request lsp.HoverRequest
 		if err := json.Unmarshal(contents, &request); err != nil {
@@ -111,7 +144,55 @@ func handleMessage(logger *log.Logger, writer io.Writer, state analysis.State, m
 			return 
 		}
 		
-		response := state.Definition(request.Id, request.Params.TextDocument.URI, request.Params.Position)
+		response := state.Definition(logger, request.Id, request.Params.TextDocument.URI, request.Params.Position)
+		writeResponse(writer, response)
+
+	case "textDocument/references":
+		var request lsp.ReferencesRequest
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("textDocument/references: %s", err)
+			return 
+		}
+
+		response := state.References(logger, request.Id, request.Params)
+		writeResponse(writer, response)
+
+	case "textDocument/prepareRename":
+		var request lsp.PrepareRenameRequest
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("textDocument/prepareRename: %s", err)
+			return 
+		}
+
+		response, err := state.PrepareRename(request.Id, request.Params.TextDocument.URI, request.Params.Position)
+		if err != nil {
+			writeError(writer, request.Id, err)
+			return
+		}
+		writeResponse(writer, response)
+
+	case "textDocument/rename":
+		var request lsp.RenameRequest
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("textDocument/rename: %s", err)
+			return 
+		}
+
+		response, err := state.Rename(request.Id, request.Params)
+		if err != nil {
+			writeError(writer, request.Id, err)
+			return
+		}
+		writeResponse(writer, response)
+
+	case "textDocument/signatureHelp":
+		var request lsp.SignatureHelpRequest
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("textDocument/signatureHelp: %s", err)
+			return 
+		}
+
+		response := state.SignatureHelp(request.Id, request.Params.TextDocument.URI, request.Params.Position)
 		writeResponse(writer, response)
 
 	case "textDocument/codeAction":
@@ -121,7 +202,7 @@ func handleMessage(logger *log.Logger, writer io.Writer, state analysis.State, m
 			return 
 		}
 		
-		response := state.CodeAction(request.Id, request.Params.TextDocument.URI) 
+		response := state.CodeAction(request.Id, request.Params) 
 		writeResponse(writer, response)
 
 	case "textDocument/completion":
@@ -131,16 +212,55 @@ func handleMessage(logger *log.Logger, writer io.Writer, state analysis.State, m
 			return 
 		}
 		
-		response := state.Completion(request.Id, request.Params.TextDocument.URI)
+		response := state.Completion(request.Id, request.Params.TextDocument.URI, request.Params.Position)
+		writeResponse(writer, response)
+
+	case "completionItem/resolve":
+		var request lsp.CompletionItemResolveRequest
+		if err := json.Unmarshal(contents, &request); err != nil {
+			logger.Printf("completionItem/resolve: %s", err)
+			return 
+		}
+
+		response := state.ResolveCompletion(request.Id, request.Params)
 		writeResponse(writer, response)
 	}
 }
 
+func publishDiagnostics(writer io.Writer, all []lsp.DiagnosticParams) {
+	for _, params := range all {
+		writeResponse(writer, lsp.DiagnosticNotification{
+			Notification: lsp.Notification{
+				RPC: "2.0",
+				Method: "textDocument/publishDiagnostics",
+			},
+			Params: params,
+		})
+	}
+}
+
 func writeResponse(writer io.Writer, msg any) {
 	reply := rpc.EncodeMessage(msg)
 	writer.Write([]byte(reply))
 }
 
+// writeError answers a request that cannot be served, the message is shown to the user.
+func writeError(writer io.Writer, id int, err error) {
+	writeResponse(writer, lsp.ErrorResponse{
+		Response: lsp.Response{RPC: "2.0", Id: &id},
+		Error: lsp.ResponseError{Code: lsp.ERROR_REQUEST_FAILED, Message: err.Error()},
+	})
+}
+
+// catalogPath is the function catalog next to the executable.
+func catalogPath() string {
+	executable, err := os.Executable()
+	if err != nil {
+		return "bormfuncs.csv"
+	}
+	return filepath.Join(filepath.Dir(executable), "bormfuncs.csv")
+}
+
 func getLogger(filename string) *log.Logger {
 	logfile, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
 	if err != nil {